@@ -0,0 +1,76 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 当前时间来源，测试中可替换为固定时间
+type Clock interface {
+	Now() time.Time
+}
+
+// Real 系统时钟
+type Real struct{}
+
+// Now 返回系统当前时间
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed 固定时间的时钟，可手动推进
+type Fixed struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixed 创建固定在 t 的时钟
+func NewFixed(t time.Time) *Fixed {
+	return &Fixed{now: t}
+}
+
+// Now 返回当前固定的时间
+func (f *Fixed) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set 将时钟设置为 t
+func (f *Fixed) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Advance 将时钟向前推进 d
+func (f *Fixed) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+var (
+	mu      sync.RWMutex
+	current Clock = Real{}
+)
+
+// Now 返回全局时钟的当前时间，业务代码统一通过此函数获取当前时间
+func Now() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.Now()
+}
+
+// Set 替换全局时钟，返回恢复原时钟的函数，供测试使用
+func Set(c Clock) (restore func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	prev := current
+	current = c
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		current = prev
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -22,6 +24,11 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
+// UpdateProfileRequest 更新个人设置请求
+type UpdateProfileRequest struct {
+	Timezone *string `json:"timezone"` // 空字符串表示使用全局时区
+}
+
 // Login 登录
 func Login(c *gin.Context) {
 	var req LoginRequest
@@ -87,3 +94,69 @@ func ChangePassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "密码修改成功"})
 }
+
+// GetProfile 获取当前用户的个人设置
+func GetProfile(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var admin model.Admin
+	if err := model.GetDB().First(&admin, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profileResponse(&admin))
+}
+
+// UpdateProfile 更新当前用户的个人设置
+func UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	var admin model.Admin
+	if err := model.GetDB().First(&admin, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if err := model.ValidateTimezone(timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := model.GetDB().Model(&admin).Update("timezone", timezone).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新个人设置失败"})
+			return
+		}
+		admin.Timezone = timezone
+		model.InvalidateSettings()
+	}
+
+	c.JSON(http.StatusOK, profileResponse(&admin))
+}
+
+// profileResponse 个人设置响应，location 为实际生效的时区
+func profileResponse(admin *model.Admin) gin.H {
+	return gin.H{
+		"username": admin.Username,
+		"timezone": admin.Timezone,
+		"location": admin.Location().String(),
+	}
+}
+
+// userLocation 当前登录用户使用的时区，未设置时使用全局时区
+func userLocation(c *gin.Context) *time.Location {
+	userID, _ := c.Get("user_id")
+
+	var admin model.Admin
+	if err := model.GetDB().First(&admin, userID).Error; err != nil {
+		return model.GlobalLocation()
+	}
+	return admin.Location()
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/clock"
+	"subdock/internal/service"
+)
+
+// maxBackupSize 恢复时允许上传的备份大小上限
+const maxBackupSize = 50 << 20
+
+// ExportBackup 导出整个实例的 JSON 备份
+// 查询参数 include_secrets=true 时包含用户密码哈希和通知密钥
+func ExportBackup(c *gin.Context) {
+	includeSecrets, _ := strconv.ParseBool(c.Query("include_secrets"))
+
+	backup, err := service.ExportBackup(includeSecrets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出备份失败"})
+		return
+	}
+
+	filename := "subdock-backup-" + clock.Now().Format("20060102-150405") + ".json"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.JSON(http.StatusOK, backup)
+}
+
+// RestoreBackup 从 JSON 备份恢复
+// 请求体可以是备份 JSON，也可以是 multipart 表单中的 file 字段；
+// 查询参数 mode 为 merge（默认，合并到现有数据）或 replace（清空后恢复），整个恢复在一个事务中完成
+func RestoreBackup(c *gin.Context) {
+	mode := c.DefaultQuery("mode", service.RestoreModeMerge)
+	if mode != service.RestoreModeMerge && mode != service.RestoreModeReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode 参数应为 merge 或 replace"})
+		return
+	}
+
+	var backup service.Backup
+	if file, err := c.FormFile("file"); err == nil {
+		if file.Size > maxBackupSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "备份文件不能超过 50MB"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+			return
+		}
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&backup); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "备份文件格式错误"})
+			return
+		}
+	} else {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize)
+		if err := json.NewDecoder(c.Request.Body).Decode(&backup); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "备份文件格式错误"})
+			return
+		}
+	}
+
+	if err := service.ValidateBackup(&backup); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := service.RestoreBackup(&backup, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "恢复完成",
+		"result":  result,
+	})
+}
+
+// ListSnapshots 列出数据库快照及当前保留策略
+func ListSnapshots(c *gin.Context) {
+	snapshots, err := service.ListSnapshots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取快照列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   service.GetSettingBool(service.SettingBackupEnabled),
+		"retention": snapshotRetention(),
+		"snapshots": snapshots,
+	})
+}
+
+// CreateSnapshot 立即生成数据库快照，并按保留策略清理旧的定时快照
+func CreateSnapshot(c *gin.Context) {
+	snapshot, err := service.CreateSnapshot("manual")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	removed, err := service.RotateSnapshots(snapshotRetention())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理旧快照失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"snapshot": snapshot,
+		"removed":  removed,
+	})
+}
+
+// DownloadSnapshot 下载数据库快照
+func DownloadSnapshot(c *gin.Context) {
+	name := c.Param("name")
+	path, err := service.SnapshotPath(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileAttachment(path, name)
+}
+
+// RestoreSnapshot 用快照替换当前数据库，恢复前会自动为当前数据库生成快照
+func RestoreSnapshot(c *gin.Context) {
+	safety, err := service.RestoreSnapshot(c.Param("name"))
+	if errors.Is(err, service.ErrSnapshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复失败: " + err.Error(), "pre_restore": safety})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "恢复完成",
+		"pre_restore": safety,
+	})
+}
+
+// DeleteSnapshot 删除数据库快照
+func DeleteSnapshot(c *gin.Context) {
+	path, err := service.SnapshotPath(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除快照失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// snapshotRetention 读取快照保留策略设置
+func snapshotRetention() service.SnapshotRetention {
+	return service.ParseSnapshotRetention(
+		service.GetSetting(service.SettingBackupKeepLast),
+		service.GetSetting(service.SettingBackupKeepDaily),
+		service.GetSetting(service.SettingBackupKeepWeekly),
+	)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+	"subdock/internal/service"
+)
+
+// CreateBudgetRequest 创建预算请求
+type CreateBudgetRequest struct {
+	Name       string  `json:"name" binding:"required"`
+	Period     string  `json:"period" binding:"required,oneof=month year"`
+	CategoryID *uint   `json:"category_id"`
+	Amount     float64 `json:"amount" binding:"gt=0"`
+	Currency   string  `json:"currency"`
+	Thresholds string  `json:"thresholds"`
+	Enabled    *bool   `json:"enabled"`
+}
+
+// UpdateBudgetRequest 更新预算请求
+type UpdateBudgetRequest struct {
+	Name       string   `json:"name"`
+	Period     string   `json:"period" binding:"omitempty,oneof=month year"`
+	CategoryID *uint    `json:"category_id"` // 传 0 表示改为总预算
+	Amount     *float64 `json:"amount"`
+	Currency   string   `json:"currency"`
+	Thresholds string   `json:"thresholds"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// ListBudgets 获取预算列表及本周期执行情况
+func ListBudgets(c *gin.Context) {
+	var budgets []model.Budget
+	if err := model.GetDB().Order("id asc").Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取预算列表失败"})
+		return
+	}
+
+	now := clock.Now().In(userLocation(c))
+	statuses := make([]service.BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := service.EvaluateBudget(budget, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "计算预算失败"})
+			return
+		}
+		statuses = append(statuses, *status)
+	}
+	c.JSON(http.StatusOK, statuses)
+}
+
+// CreateBudget 创建预算
+func CreateBudget(c *gin.Context) {
+	var req CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	thresholds := req.Thresholds
+	if thresholds == "" {
+		thresholds = "80,100"
+	}
+	if _, err := service.ParseThresholds(thresholds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = service.GetSetting(service.SettingBaseCurrency)
+	}
+
+	if req.CategoryID != nil && *req.CategoryID == 0 {
+		req.CategoryID = nil
+	}
+	if req.CategoryID != nil && !validateCategoryID(*req.CategoryID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+		return
+	}
+
+	budget := &model.Budget{
+		Name:       req.Name,
+		Period:     model.BudgetPeriod(req.Period),
+		CategoryID: req.CategoryID,
+		Amount:     req.Amount,
+		Currency:   currency,
+		Thresholds: thresholds,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if err := model.GetDB().Create(budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建预算失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, budget)
+}
+
+// UpdateBudget 更新预算
+func UpdateBudget(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	var budget model.Budget
+	if err := model.GetDB().First(&budget, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "预算不存在"})
+		return
+	}
+
+	var req UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Period != "" {
+		updates["period"] = req.Period
+	}
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			updates["category_id"] = nil
+		} else if !validateCategoryID(*req.CategoryID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			return
+		} else {
+			updates["category_id"] = *req.CategoryID
+		}
+	}
+	if req.Amount != nil {
+		if *req.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "预算金额必须大于 0"})
+			return
+		}
+		updates["amount"] = *req.Amount
+	}
+	if req.Currency != "" {
+		updates["currency"] = strings.ToUpper(req.Currency)
+	}
+	if req.Thresholds != "" {
+		if _, err := service.ParseThresholds(req.Thresholds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["thresholds"] = req.Thresholds
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+
+	if err := model.GetDB().Model(&budget).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新预算失败"})
+		return
+	}
+
+	model.GetDB().First(&budget, id)
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteBudget 删除预算及其告警记录
+func DeleteBudget(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	if err := model.GetDB().Where("budget_id = ?", id).Delete(&model.BudgetAlert{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除预算失败"})
+		return
+	}
+	if err := model.GetDB().Delete(&model.Budget{}, id).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除预算失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
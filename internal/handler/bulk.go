@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/model"
+)
+
+// 批量操作类型
+const (
+	BulkActionDelete           = "delete"
+	BulkActionRenew            = "renew"
+	BulkActionSetCategory      = "set_category"
+	BulkActionSetRemindDays    = "set_remind_days" // 旧版，换算为提醒偏移
+	BulkActionSetRemindOffsets = "set_remind_offsets"
+	BulkActionSetAutoRenew     = "set_auto_renew"
+	BulkActionSetStatus        = "set_status"
+)
+
+// errBulkRollback 用于在 dry-run 模式下回滚事务
+var errBulkRollback = errors.New("dry run")
+
+// BulkSubscriptionRequest 批量操作请求
+type BulkSubscriptionRequest struct {
+	Action        string `json:"action" binding:"required,oneof=delete renew set_category set_remind_days set_remind_offsets set_auto_renew set_status"`
+	IDs           []uint `json:"ids" binding:"required,min=1,max=500"`
+	CategoryID    *uint  `json:"category_id"`    // set_category：传 0 或 null 表示清除分类
+	RemindDays    int    `json:"remind_days"`    // set_remind_days
+	RemindOffsets string `json:"remind_offsets"` // set_remind_offsets：传空字符串表示改用全局默认
+	AutoRenew     *bool  `json:"auto_renew"`     // set_auto_renew：不传则逐个切换
+	Status        string `json:"status"`         // set_status
+	DryRun        bool   `json:"dry_run"`        // 仅预览结果，不写入数据库
+}
+
+// BulkItemResult 单个订阅的批量操作结果
+type BulkItemResult struct {
+	ID           uint                `json:"id"`
+	Success      bool                `json:"success"`
+	Error        string              `json:"error,omitempty"`
+	Subscription *model.Subscription `json:"subscription,omitempty"` // 操作后的订阅，删除时为删除前的数据
+}
+
+// BulkSubscriptions 在一个事务中对多个订阅执行同一操作
+// 单个订阅失败（如不存在）只记录在结果中；数据库错误会回滚整个事务
+func BulkSubscriptions(c *gin.Context) {
+	var req BulkSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误: " + err.Error()})
+		return
+	}
+
+	switch req.Action {
+	case BulkActionSetCategory:
+		if req.CategoryID != nil && *req.CategoryID > 0 && !validateCategoryID(*req.CategoryID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			return
+		}
+	case BulkActionSetRemindDays:
+		if req.RemindDays <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "remind_days 必须大于 0"})
+			return
+		}
+		req.Action = BulkActionSetRemindOffsets
+		req.RemindOffsets = model.LegacyRemindOffsets(req.RemindDays)
+	case BulkActionSetRemindOffsets:
+		offsets, err := model.NormalizeRemindOffsets(req.RemindOffsets)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.RemindOffsets = offsets
+	case BulkActionSetStatus:
+		switch model.SubscriptionStatus(req.Status) {
+		case model.StatusActive, model.StatusPaused, model.StatusCancelled:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status 应为 active、paused 或 cancelled"})
+			return
+		}
+	}
+
+	results := make([]BulkItemResult, 0, len(req.IDs))
+	err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		seen := make(map[uint]bool)
+		for _, id := range req.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			var subscription model.Subscription
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&subscription, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					results = append(results, BulkItemResult{ID: id, Error: "订阅不存在"})
+					continue
+				}
+				return err
+			}
+
+			if err := applyBulkAction(tx, &req, &subscription); err != nil {
+				// 不可续订的订阅跳过，不影响其余订阅
+				if errors.Is(err, model.ErrNotRenewable) || errors.Is(err, model.ErrCycleExhausted) {
+					results = append(results, BulkItemResult{ID: id, Error: err.Error()})
+					continue
+				}
+				return err
+			}
+
+			if req.Action != BulkActionDelete {
+				if err := tx.Preload("Category").Preload("Tags").First(&subscription, id).Error; err != nil {
+					return err
+				}
+			}
+			results = append(results, BulkItemResult{ID: id, Success: true, Subscription: &subscription})
+		}
+
+		if req.DryRun {
+			return errBulkRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkRollback) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "批量操作失败: " + err.Error()})
+		return
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"dry_run":   req.DryRun,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// applyBulkAction 对单个订阅执行批量操作
+func applyBulkAction(tx *gorm.DB, req *BulkSubscriptionRequest, subscription *model.Subscription) error {
+	switch req.Action {
+	case BulkActionDelete:
+		return tx.Delete(&model.Subscription{}, subscription.ID).Error
+	case BulkActionRenew:
+		_, err := subscription.Renew(tx, subscription.ExpireDate)
+		return err
+	case BulkActionSetCategory:
+		var categoryID interface{}
+		if req.CategoryID != nil && *req.CategoryID > 0 {
+			categoryID = *req.CategoryID
+		}
+		return tx.Model(subscription).Update("category_id", categoryID).Error
+	case BulkActionSetRemindOffsets:
+		return tx.Model(subscription).Update("remind_offsets", req.RemindOffsets).Error
+	case BulkActionSetAutoRenew:
+		autoRenew := !subscription.AutoRenew
+		if req.AutoRenew != nil {
+			autoRenew = *req.AutoRenew
+		}
+		if autoRenew && !subscription.IsRecurring() {
+			return model.ErrNotRenewable
+		}
+		return tx.Model(subscription).Update("auto_renew", autoRenew).Error
+	case BulkActionSetStatus:
+		return tx.Model(subscription).Update("status", req.Status).Error
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/config"
+	"subdock/internal/model"
+	"subdock/internal/service"
+)
+
+// CalendarFeed 输出 iCalendar 订阅源
+// 通过查询参数 token 鉴权（每个用户独立的日历令牌），日历客户端无需登录即可订阅；
+// 订阅数据由实例内所有用户共享，日历包含全部生效订阅，令牌所属用户只决定按哪个时区计算今天；
+// months 预计续订的月数（默认 12，最多 36），并支持订阅列表的筛选参数，如 category_id、tag
+func CalendarFeed(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少日历令牌"})
+		return
+	}
+
+	var admin model.Admin
+	result := model.GetDB().Where("calendar_token = ?", token).Limit(1).Find(&admin)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询日历令牌失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的日历令牌"})
+		return
+	}
+
+	months := 12
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 36 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months 参数应为 1-36"})
+			return
+		}
+		months = n
+	}
+
+	query, err := applySubscriptionFilters(c, subscriptionQuery().Where("status = ?", model.StatusActive))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var subscriptions []model.Subscription
+	if err := query.Order("id asc").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅列表失败"})
+		return
+	}
+
+	today := model.Today(admin.Location())
+	ics := service.BuildCalendar(subscriptions, service.CalendarOptions{
+		Name:  config.Get().WebsiteTitle + " 订阅",
+		From:  today,
+		Until: today.AddDate(0, months, 0),
+	})
+	c.Header("Content-Disposition", `inline; filename="subdock.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(ics))
+}
+
+// GetCalendarToken 获取当前用户的日历订阅地址，首次调用时生成令牌
+func GetCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var admin model.Admin
+	if err := model.GetDB().First(&admin, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if admin.CalendarToken == "" {
+		if err := resetCalendarToken(&admin); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成日历令牌失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, calendarTokenResponse(&admin))
+}
+
+// ResetCalendarToken 重新生成日历令牌，旧的订阅地址随即失效
+func ResetCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var admin model.Admin
+	if err := model.GetDB().First(&admin, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if err := resetCalendarToken(&admin); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成日历令牌失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, calendarTokenResponse(&admin))
+}
+
+// resetCalendarToken 生成并保存新的日历令牌
+func resetCalendarToken(admin *model.Admin) error {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	admin.CalendarToken = hex.EncodeToString(buf)
+	return model.GetDB().Model(admin).Update("calendar_token", admin.CalendarToken).Error
+}
+
+// calendarTokenResponse 日历令牌响应，path 为相对订阅地址
+func calendarTokenResponse(admin *model.Admin) gin.H {
+	return gin.H{
+		"token": admin.CalendarToken,
+		"path":  "/api/calendar.ics?token=" + url.QueryEscape(admin.CalendarToken),
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"subdock/internal/model"
+)
+
+// CategoryRequest 创建/更新分类请求
+type CategoryRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+}
+
+// TagWithCount 标签及其关联的订阅数量
+type TagWithCount struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// ListCategories 获取分类列表
+func ListCategories(c *gin.Context) {
+	var categories []model.Category
+	if err := model.GetDB().Order("name asc").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分类列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory 创建分类
+func CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误，分类名称不能为空"})
+		return
+	}
+
+	category := &model.Category{
+		Name:  strings.TrimSpace(req.Name),
+		Color: req.Color,
+		Icon:  req.Icon,
+	}
+	if err := model.GetDB().Create(category).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "创建分类失败，名称可能已存在"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory 更新分类
+func UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	var category model.Category
+	if err := model.GetDB().First(&category, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "分类不存在"})
+		return
+	}
+
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if name := strings.TrimSpace(req.Name); name != "" {
+		updates["name"] = name
+	}
+	if req.Color != "" {
+		updates["color"] = req.Color
+	}
+	if req.Icon != "" {
+		updates["icon"] = req.Icon
+	}
+
+	if err := model.GetDB().Model(&category).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "更新分类失败，名称可能已存在"})
+		return
+	}
+
+	model.GetDB().First(&category, id)
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory 删除分类，关联订阅变为未分类；存在分类预算时拒绝删除
+func DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	var budgetCount int64
+	model.GetDB().Model(&model.Budget{}).Where("category_id = ?", id).Count(&budgetCount)
+	if budgetCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该分类下存在预算，请先删除预算"})
+		return
+	}
+
+	err = model.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Subscription{}).Where("category_id = ?", id).Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Category{}, id).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除分类失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// ListTags 获取标签列表及使用次数
+func ListTags(c *gin.Context) {
+	var tags []TagWithCount
+	err := model.GetDB().Model(&model.Tag{}).
+		Select("tags.id, tags.name, COUNT(subscriptions.id) AS count").
+		Joins("LEFT JOIN subscription_tags ON subscription_tags.tag_id = tags.id").
+		Joins("LEFT JOIN subscriptions ON subscriptions.id = subscription_tags.subscription_id AND subscriptions.deleted_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("tags.name asc").
+		Scan(&tags).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取标签列表失败"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// DeleteTag 删除标签及其关联
+func DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	err = model.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM subscription_tags WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Tag{}, id).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除标签失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "删除成功"})
+}
+
+// resolveTags 按名称查找标签，不存在时创建
+func resolveTags(tx *gorm.DB, names []string) ([]model.Tag, error) {
+	tags := []model.Tag{}
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag model.Tag
+		if err := tx.Where(model.Tag{Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// validateCategoryID 校验分类是否存在
+func validateCategoryID(id uint) bool {
+	var count int64
+	model.GetDB().Model(&model.Category{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
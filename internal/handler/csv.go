@@ -0,0 +1,423 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+	"subdock/internal/service"
+)
+
+// 导入预览中每行的处理方式
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+	importActionError  = "error"
+)
+
+// csvExportHeaders 导出列，与导入字段名一致，导出文件可直接再导入
+var csvExportHeaders = []string{
+	"id", "name", "amount", "currency", "start_date", "billing_type", "cycle_value", "cycle_unit", "cycle_rule", "expire_date",
+	"support_end_date", "auto_renew", "remind_offsets", "timezone", "status", "category", "tags", "remark",
+}
+
+// ImportPreviewRow 导入预览的一行
+type ImportPreviewRow struct {
+	service.ImportRow
+	Action         string `json:"action"`
+	SubscriptionID uint   `json:"subscription_id,omitempty"` // 更新时匹配到的订阅
+	NewCategory    bool   `json:"new_category,omitempty"`    // 导入时将创建新分类
+}
+
+// ImportPreview 导入预览结果
+type ImportPreview struct {
+	Headers    []string              `json:"headers"`
+	Mapping    map[string]string     `json:"mapping"`
+	DateFormat string                `json:"date_format"`
+	Rows       []ImportPreviewRow    `json:"rows"`
+	Errors     []service.ImportError `json:"errors"`
+	Summary    map[string]int        `json:"summary"`
+}
+
+// ExportSubscriptions 导出订阅
+// 查询参数：format 目前仅支持 csv；同时支持订阅列表的筛选参数
+func ExportSubscriptions(c *gin.Context) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的导出格式: " + format})
+		return
+	}
+
+	query, err := applySubscriptionFilters(c, subscriptionQuery())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var subscriptions []model.Subscription
+	if err := query.Order("expire_date asc").Order("id asc").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出订阅失败"})
+		return
+	}
+
+	filename := "subdock-subscriptions-" + clock.Now().Format("20060102") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// 写入 UTF-8 BOM，便于 Excel 正确识别中文
+	c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	w.Write(csvExportHeaders)
+	for _, sub := range subscriptions {
+		category := ""
+		if sub.Category != nil {
+			category = sub.Category.Name
+		}
+		tags := make([]string, 0, len(sub.Tags))
+		for _, tag := range sub.Tags {
+			tags = append(tags, tag.Name)
+		}
+		// 不会到期的订阅到期日期留空
+		expireDate, supportEndDate := "", ""
+		if sub.HasExpiry() {
+			expireDate = sub.ExpireDate.Format("2006-01-02")
+		}
+		if sub.SupportEndDate != nil {
+			supportEndDate = sub.SupportEndDate.Format("2006-01-02")
+		}
+		billingType := sub.BillingType
+		if billingType == "" {
+			billingType = model.BillingRecurring
+		}
+		// 名称、分类、标签与备注由用户输入，转义可能被表格软件当作公式的内容
+		w.Write([]string{
+			strconv.FormatUint(uint64(sub.ID), 10),
+			service.EscapeCSVCell(sub.Name),
+			formatFloat(sub.Amount),
+			sub.Currency,
+			sub.StartDate.Format("2006-01-02"),
+			string(billingType),
+			strconv.Itoa(sub.CycleValue),
+			string(sub.CycleUnit),
+			sub.CycleRule,
+			expireDate,
+			supportEndDate,
+			strconv.FormatBool(sub.AutoRenew),
+			sub.RemindOffsets,
+			sub.Timezone,
+			string(sub.Status),
+			service.EscapeCSVCell(category),
+			service.EscapeCSVCell(strings.Join(tags, ";")),
+			service.EscapeCSVCell(sub.Remark),
+		})
+	}
+	w.Flush()
+}
+
+// PreviewImportSubscriptions 预览 CSV 导入结果，不写入数据库
+func PreviewImportSubscriptions(c *gin.Context) {
+	preview, ok := buildImportPreview(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// ImportSubscriptions 导入 CSV
+// multipart 表单字段：file CSV 文件，mapping 列映射 JSON（目标字段 -> 表头），date_format 日期格式，
+// update_existing 按名称更新已有订阅，skip_invalid 跳过校验失败的行（否则存在错误时整体拒绝）
+func ImportSubscriptions(c *gin.Context) {
+	preview, ok := buildImportPreview(c)
+	if !ok {
+		return
+	}
+
+	if len(preview.Errors) > 0 && !formBool(c, "skip_invalid") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV 存在校验错误，请修正后重试或开启 skip_invalid", "preview": preview})
+		return
+	}
+
+	err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		for i := range preview.Rows {
+			row := &preview.Rows[i]
+			switch row.Action {
+			case importActionCreate:
+				id, err := createImportedSubscription(tx, &row.ImportRow)
+				if err != nil {
+					return err
+				}
+				row.SubscriptionID = id
+			case importActionUpdate:
+				if err := updateImportedSubscription(tx, row.SubscriptionID, &row.ImportRow); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "导入完成",
+		"summary": preview.Summary,
+		"rows":    preview.Rows,
+		"errors":  preview.Errors,
+	})
+}
+
+// buildImportPreview 解析上传的 CSV 并确定每行的处理方式，出错时已写入响应
+func buildImportPreview(c *gin.Context) (*ImportPreview, bool) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传 CSV 文件（字段名 file）"})
+		return nil, false
+	}
+	if file.Size > 5<<20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV 文件不能超过 5MB"})
+		return nil, false
+	}
+
+	opts := service.CSVImportOptions{DateFormat: c.PostForm("date_format")}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping 应为 JSON 对象"})
+			return nil, false
+		}
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return nil, false
+	}
+	defer f.Close()
+
+	parsed, err := service.ParseSubscriptionCSV(f, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	updateExisting := formBool(c, "update_existing")
+	preview := &ImportPreview{
+		Headers:    parsed.Headers,
+		Mapping:    parsed.Mapping,
+		DateFormat: parsed.DateFormat,
+		Rows:       make([]ImportPreviewRow, 0, len(parsed.Rows)),
+		Errors:     []service.ImportError{},
+		Summary:    map[string]int{importActionCreate: 0, importActionUpdate: 0, importActionError: 0},
+	}
+
+	for _, row := range parsed.Rows {
+		item := ImportPreviewRow{ImportRow: row, Action: importActionCreate}
+		switch {
+		case len(row.Errors) > 0:
+			item.Action = importActionError
+			preview.Errors = append(preview.Errors, row.Errors...)
+		case updateExisting:
+			var existing model.Subscription
+			result := model.GetDB().Where("LOWER(name) = LOWER(?)", row.Name).Order("id asc").Limit(1).Find(&existing)
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "匹配已有订阅失败"})
+				return nil, false
+			}
+			if result.RowsAffected > 0 {
+				item.Action = importActionUpdate
+				item.SubscriptionID = existing.ID
+			}
+		}
+		if item.Action != importActionError && row.Category != "" {
+			var count int64
+			model.GetDB().Model(&model.Category{}).Where("name = ?", row.Category).Count(&count)
+			item.NewCategory = count == 0
+		}
+		preview.Summary[item.Action]++
+		preview.Rows = append(preview.Rows, item)
+	}
+	return preview, true
+}
+
+// createImportedSubscription 按导入行创建订阅
+func createImportedSubscription(tx *gorm.DB, row *service.ImportRow) (uint, error) {
+	subscription := &model.Subscription{
+		Name:          row.Name,
+		Amount:        row.Amount,
+		Currency:      row.Currency,
+		StartDate:     row.StartDate,
+		BillingType:   row.BillingType,
+		CycleValue:    row.CycleValue,
+		CycleUnit:     row.CycleUnit,
+		CycleRule:     row.CycleRule,
+		AutoRenew:     row.AutoRenew,
+		AnchorDay:     importAnchorDay(row),
+		RemindOffsets: row.RemindOffsets,
+		Timezone:      row.Timezone,
+		Status:        row.Status,
+		Remark:        row.Remark,
+
+		SupportEndDate: row.SupportEndDate,
+	}
+	if row.BillingType == model.BillingLifetime || (row.BillingType == model.BillingOneTime && row.ExpireDate == nil) {
+		subscription.ExpireDate = model.NoExpireDate
+	} else if row.ExpireDate != nil {
+		subscription.ExpireDate = *row.ExpireDate
+	} else {
+		subscription.ExpireDate = subscription.CalculateExpireDate()
+	}
+
+	categoryID, err := resolveCategoryByName(tx, row.Category)
+	if err != nil {
+		return 0, err
+	}
+	subscription.CategoryID = categoryID
+
+	tags, err := resolveTags(tx, row.Tags)
+	if err != nil {
+		return 0, err
+	}
+	subscription.Tags = tags
+
+	if err := tx.Create(subscription).Error; err != nil {
+		return 0, err
+	}
+	return subscription.ID, nil
+}
+
+// updateImportedSubscription 按导入行更新已有订阅，只更新 CSV 中提供的字段
+func updateImportedSubscription(tx *gorm.DB, id uint, row *service.ImportRow) error {
+	var subscription model.Subscription
+	if err := tx.First(&subscription, id).Error; err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	cycleRelatedChanged := false
+
+	if row.Present[service.ImportFieldAmount] {
+		updates["amount"] = row.Amount
+	}
+	if row.Present[service.ImportFieldCurrency] {
+		updates["currency"] = row.Currency
+	}
+	if row.Present[service.ImportFieldStartDate] && !row.StartDate.Equal(subscription.StartDate) {
+		updates["start_date"] = row.StartDate
+		subscription.StartDate = row.StartDate
+		cycleRelatedChanged = true
+	}
+	if row.Present[service.ImportFieldCycle] {
+		updates["cycle_value"] = row.CycleValue
+		updates["cycle_unit"] = row.CycleUnit
+		updates["cycle_rule"] = row.CycleRule
+		cycleRelatedChanged = cycleRelatedChanged || row.CycleValue != subscription.CycleValue ||
+			row.CycleUnit != subscription.CycleUnit || row.CycleRule != subscription.CycleRule
+		subscription.CycleValue = row.CycleValue
+		subscription.CycleUnit = row.CycleUnit
+		subscription.CycleRule = row.CycleRule
+	}
+	if row.Present[service.ImportFieldBillingType] && row.BillingType != subscription.BillingType {
+		updates["billing_type"] = row.BillingType
+		cycleRelatedChanged = cycleRelatedChanged || !subscription.IsRecurring()
+		subscription.BillingType = row.BillingType
+	}
+	if row.Present[service.ImportFieldSupportEnd] {
+		updates["support_end_date"] = *row.SupportEndDate
+	}
+	// 周期订阅提供到期日期时账单日取到期日期的日，否则修改开始日期时取开始日期的日
+	anchorDay := subscription.AnchorDay
+	if row.Present[service.ImportFieldExpireDate] && subscription.IsRecurring() {
+		anchorDay = row.ExpireDate.Day()
+	} else if _, ok := updates["start_date"]; ok {
+		anchorDay = row.StartDate.Day()
+	}
+	if anchorDay != subscription.AnchorDay {
+		updates["anchor_day"] = anchorDay
+		subscription.AnchorDay = anchorDay
+	}
+	switch {
+	case subscription.BillingType == model.BillingLifetime:
+		updates["expire_date"] = model.NoExpireDate
+		updates["auto_renew"] = false
+	case !subscription.IsRecurring():
+		if row.Present[service.ImportFieldExpireDate] {
+			updates["expire_date"] = *row.ExpireDate
+		}
+		updates["auto_renew"] = false
+	case row.Present[service.ImportFieldExpireDate]:
+		updates["expire_date"] = *row.ExpireDate
+	case cycleRelatedChanged:
+		updates["expire_date"] = subscription.CalculateExpireDate()
+	}
+	if row.Present[service.ImportFieldAutoRenew] && subscription.IsRecurring() {
+		updates["auto_renew"] = row.AutoRenew
+	}
+	if row.Present[service.ImportFieldRemindOffsets] {
+		updates["remind_offsets"] = row.RemindOffsets
+	}
+	if row.Present[service.ImportFieldTimezone] {
+		updates["timezone"] = row.Timezone
+	}
+	if row.Present[service.ImportFieldStatus] {
+		updates["status"] = row.Status
+	}
+	if row.Present[service.ImportFieldRemark] {
+		updates["remark"] = row.Remark
+	}
+	if row.Present[service.ImportFieldCategory] {
+		categoryID, err := resolveCategoryByName(tx, row.Category)
+		if err != nil {
+			return err
+		}
+		updates["category_id"] = categoryID
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Model(&subscription).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	if row.Present[service.ImportFieldTags] {
+		tags, err := resolveTags(tx, row.Tags)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&subscription).Association("Tags").Replace(tags)
+	}
+	return nil
+}
+
+// importAnchorDay 导入行的账单日：周期订阅提供到期日期时取到期日期的日，否则取开始日期的日
+func importAnchorDay(row *service.ImportRow) int {
+	if row.BillingType == model.BillingRecurring && row.ExpireDate != nil {
+		return row.ExpireDate.Day()
+	}
+	return row.StartDate.Day()
+}
+
+// resolveCategoryByName 按名称查找分类，不存在时创建；名称为空返回 nil
+func resolveCategoryByName(tx *gorm.DB, name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	var category model.Category
+	if err := tx.Where(model.Category{Name: name}).FirstOrCreate(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category.ID, nil
+}
+
+// formBool 读取表单中的布尔字段
+func formBool(c *gin.Context, key string) bool {
+	v, _ := strconv.ParseBool(c.PostForm(key))
+	return v
+}
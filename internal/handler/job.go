@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/scheduler"
+)
+
+// ListJobs 获取定时任务状态：调度规则、最近一次执行时间、耗时、结果与下次执行时间
+func ListJobs(sched *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, sched.Jobs())
+	}
+}
+
+// RunJob 立即执行定时任务，等待执行完成后返回任务状态
+func RunJob(sched *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := sched.RunJob(c.Param("name"))
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, scheduler.ErrJobRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "任务执行失败: " + err.Error(), "job": status})
+		default:
+			c.JSON(http.StatusOK, status)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/service"
+)
+
+// ListRates 获取各币种最新汇率
+func ListRates(c *gin.Context) {
+	rates, err := service.LatestRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取汇率失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": service.GetSetting(service.SettingBaseCurrency),
+		"rates":         rates,
+	})
+}
+
+// RefreshRates 立即从配置的汇率源刷新汇率
+func RefreshRates(c *gin.Context) {
+	kind := service.GetSetting(service.SettingRateProvider)
+	if kind == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请先配置汇率源"})
+		return
+	}
+
+	provider, err := service.NewRateProvider(kind, service.GetSetting(service.SettingRateSourceURL))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	count, err := service.RefreshRates(ctx, provider)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "刷新汇率失败: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "汇率已刷新", "count": count})
+}
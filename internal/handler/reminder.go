@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+	"subdock/internal/service"
+)
+
+// SnoozeRequest 暂停提醒请求，days 与 until 二选一
+type SnoozeRequest struct {
+	Days  int    `json:"days" binding:"omitempty,min=1,max=365"`
+	Until string `json:"until"` // YYYY-MM-DD，当天起恢复提醒
+}
+
+// reminderPage 一键操作链接的确认与结果页面
+// GET 只展示页面并自动提交表单，实际操作通过 POST 完成，避免聊天软件预览链接时误触发
+var reminderPage = template.Must(template.New("reminder").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>SubDock</title></head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 15vh;">
+{{if .Form}}<form id="f" method="post"><button type="submit" style="font-size: 1.2em; padding: .5em 1.5em;">{{.Message}}</button></form>
+<script>document.getElementById('f').submit();</script>{{else}}<p style="font-size: 1.2em;">{{.Message}}</p>{{end}}
+</body>
+</html>`))
+
+// AcknowledgeSubscription 确认已知晓本周期的到期提醒
+func AcknowledgeSubscription(c *gin.Context) {
+	reminderStateAction(c, service.AcknowledgeSubscription)
+}
+
+// UnacknowledgeSubscription 撤销本周期的提醒确认
+func UnacknowledgeSubscription(c *gin.Context) {
+	reminderStateAction(c, service.UnacknowledgeSubscription)
+}
+
+// SnoozeSubscription 暂停提醒
+func SnoozeSubscription(c *gin.Context) {
+	var req SnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+
+	switch {
+	case req.Until != "":
+		until, err := time.Parse("2006-01-02", req.Until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until 格式错误，应为 YYYY-MM-DD"})
+			return
+		}
+		reminderStateAction(c, func(id uint) (*model.Subscription, error) {
+			return service.SnoozeSubscriptionUntil(id, until)
+		})
+	case req.Days > 0:
+		reminderStateAction(c, func(id uint) (*model.Subscription, error) {
+			return service.SnoozeSubscription(id, req.Days)
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请提供 days 或 until"})
+	}
+}
+
+// UnsnoozeSubscription 取消提醒暂停
+func UnsnoozeSubscription(c *gin.Context) {
+	reminderStateAction(c, service.UnsnoozeSubscription)
+}
+
+// ReminderLinkPage 展示一键操作链接页面
+func ReminderLinkPage(c *gin.Context) {
+	action, ok := parseReminderLink(c)
+	if !ok {
+		return
+	}
+
+	label := "确认已知晓本次到期提醒"
+	if action.Action == service.ReminderActionSnooze {
+		label = "暂停提醒 " + strconv.Itoa(action.Days) + " 天"
+	}
+	renderReminderPage(c, http.StatusOK, label, true)
+}
+
+// ReminderLinkAction 执行一键操作链接
+func ReminderLinkAction(c *gin.Context) {
+	action, ok := parseReminderLink(c)
+	if !ok {
+		return
+	}
+
+	var sub *model.Subscription
+	var err error
+	if action.Action == service.ReminderActionSnooze {
+		sub, err = service.SnoozeSubscription(action.SubscriptionID, action.Days)
+	} else {
+		sub, err = service.AcknowledgeSubscription(action.SubscriptionID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		renderReminderPage(c, http.StatusNotFound, "订阅不存在", false)
+		return
+	}
+	if err != nil {
+		renderReminderPage(c, http.StatusInternalServerError, "操作失败，请稍后重试", false)
+		return
+	}
+
+	message := "✅ 已确认「" + sub.Name + "」的到期提醒，本周期内不再提醒"
+	if action.Action == service.ReminderActionSnooze {
+		message = "😴 「" + sub.Name + "」的提醒已暂停至 " + sub.SnoozeUntil.Format("2006-01-02")
+	}
+	renderReminderPage(c, http.StatusOK, message, false)
+}
+
+// parseReminderLink 解析并校验链接参数，失败时已写入响应
+func parseReminderLink(c *gin.Context) (*service.ReminderAction, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		renderReminderPage(c, http.StatusBadRequest, "链接无效", false)
+		return nil, false
+	}
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	days, _ := strconv.Atoi(c.Query("days"))
+
+	action := &service.ReminderAction{
+		SubscriptionID: uint(id),
+		Action:         c.Param("action"),
+		Days:           days,
+		Expires:        expires,
+	}
+	if err := action.Verify(c.Query("sig"), clock.Now()); err != nil {
+		renderReminderPage(c, http.StatusForbidden, err.Error(), false)
+		return nil, false
+	}
+	if action.Action == service.ReminderActionSnooze && (days <= 0 || days > 365) {
+		renderReminderPage(c, http.StatusBadRequest, "链接无效", false)
+		return nil, false
+	}
+	return action, true
+}
+
+// renderReminderPage 输出链接页面，form 为 true 时展示提交按钮
+func renderReminderPage(c *gin.Context, status int, message string, form bool) {
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	reminderPage.Execute(c.Writer, gin.H{"Message": message, "Form": form})
+}
+
+// reminderStateAction 按路径中的 ID 执行提醒状态操作并返回更新后的订阅
+func reminderStateAction(c *gin.Context, fn func(id uint) (*model.Subscription, error)) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的 ID"})
+		return
+	}
+
+	if _, err := fn(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新提醒状态失败"})
+		return
+	}
+
+	var subscription model.Subscription
+	subscriptionQuery().First(&subscription, id)
+	c.JSON(http.StatusOK, subscription)
+}
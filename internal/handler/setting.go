@@ -1,30 +1,65 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"subdock/internal/clock"
 	"subdock/internal/model"
 	"subdock/internal/service"
 )
 
 // SettingsResponse 设置响应
 type SettingsResponse struct {
-	NotifyHours    string `json:"notify_hours"`
-	TelegramBotToken string `json:"telegram_bot_token"`
-	TelegramChatID   string `json:"telegram_chat_id"`
-	BarkURL          string `json:"bark_url"`
+	NotifyHours            string `json:"notify_hours"`    // 旧版通知时段，已由 notify_schedule 取代
+	NotifySchedule         string `json:"notify_schedule"` // 实际使用的通知计划 cron 表达式
+	QuietHours             string `json:"quiet_hours"`     // 免打扰时段，为空表示不启用
+	TelegramBotToken       string `json:"telegram_bot_token"`
+	TelegramChatID         string `json:"telegram_chat_id"`
+	BarkURL                string `json:"bark_url"`
+	RateProvider           string `json:"rate_provider"`
+	RateSourceURL          string `json:"rate_source_url"`
+	BaseCurrency           string `json:"base_currency"`
+	BackupEnabled          string `json:"backup_enabled"`
+	BackupKeepLast         string `json:"backup_keep_last"`
+	BackupKeepDaily        string `json:"backup_keep_daily"`
+	BackupKeepWeekly       string `json:"backup_keep_weekly"`
+	TelegramAPIURL         string `json:"telegram_api_url"`
+	TelegramBotEnabled     string `json:"telegram_bot_enabled"`
+	TelegramAllowedChatIDs string `json:"telegram_allowed_chat_ids"`
+	PublicURL              string `json:"public_url"`
+	RemindOffsets          string `json:"remind_offsets"`
+	Timezone               string `json:"timezone"`          // 为空表示使用服务器本地时区
+	AutoRenewPolicy        string `json:"auto_renew_policy"` // 过期多个周期时的自动续订策略：backfill 或 rebase
+	NotifyMode             string `json:"notify_mode"`       // 通知方式：individual 逐条提醒或 digest 摘要
+	DigestCadence          string `json:"digest_cadence"`    // 摘要频率：daily 或 weekly
+	DigestSchedule         string `json:"digest_schedule"`   // 实际使用的摘要发送计划 cron 表达式
+	DigestDays             string `json:"digest_days"`       // 摘要列出未来 N 天内到期的订阅
+
+	OverdueRemindInterval         string `json:"overdue_remind_interval"` // 过期后每隔 N 天提醒一次，0 表示不提醒
+	OverdueRemindMax              string `json:"overdue_remind_max"`      // 过期提醒的最多次数
+	OverdueEscalateAfter          string `json:"overdue_escalate_after"`  // 过期 N 天后同时发送到升级渠道，0 表示不升级
+	OverdueEscalateTelegramChatID string `json:"overdue_escalate_telegram_chat_id"`
+	OverdueEscalateBarkURL        string `json:"overdue_escalate_bark_url"`
 }
 
-// UpdateSettingsRequest 更新设置请求
-type UpdateSettingsRequest struct {
-	NotifyHours      string `json:"notify_hours"`
-	TelegramBotToken string `json:"telegram_bot_token"`
-	TelegramChatID   string `json:"telegram_chat_id"`
-	BarkURL          string `json:"bark_url"`
+// UpdateSettingsRequest 更新设置请求：设置项名称到新值，未提供的设置项保持不变，null 或空字符串表示清除（恢复默认值）
+type UpdateSettingsRequest map[string]*string
+
+// NotifySchedulePreviewRequest 通知计划预览请求，未提供的字段使用当前设置
+type NotifySchedulePreviewRequest struct {
+	NotifySchedule string `json:"notify_schedule"`
+	QuietHours     string `json:"quiet_hours"`
+	Count          int    `json:"count" binding:"omitempty,min=1,max=50"` // 预览次数，默认 5
 }
 
+// defaultPreviewCount 通知计划默认预览次数
+const defaultPreviewCount = 5
+
 // TestNotifyRequest 测试通知请求
 type TestNotifyRequest struct {
 	Type string `json:"type" binding:"required,oneof=telegram bark"`
@@ -33,16 +68,42 @@ type TestNotifyRequest struct {
 // GetSettings 获取设置
 func GetSettings(c *gin.Context) {
 	settings := SettingsResponse{
-		NotifyHours:      getSetting("notify_hours", "9"),
-		TelegramBotToken: getSetting("telegram_bot_token", ""),
-		TelegramChatID:   getSetting("telegram_chat_id", ""),
-		BarkURL:          getSetting("bark_url", ""),
+		NotifyHours:            service.GetSetting(service.SettingNotifyHours),
+		NotifySchedule:         currentNotifySchedule(),
+		QuietHours:             service.GetSetting(service.SettingQuietHours),
+		TelegramBotToken:       service.GetSetting(service.SettingTelegramBotToken),
+		TelegramChatID:         service.GetSetting(service.SettingTelegramChatID),
+		BarkURL:                service.GetSetting(service.SettingBarkURL),
+		RateProvider:           service.GetSetting(service.SettingRateProvider),
+		RateSourceURL:          service.GetSetting(service.SettingRateSourceURL),
+		BaseCurrency:           service.GetSetting(service.SettingBaseCurrency),
+		BackupEnabled:          service.GetSetting(service.SettingBackupEnabled),
+		BackupKeepLast:         service.GetSetting(service.SettingBackupKeepLast),
+		BackupKeepDaily:        service.GetSetting(service.SettingBackupKeepDaily),
+		BackupKeepWeekly:       service.GetSetting(service.SettingBackupKeepWeekly),
+		TelegramAPIURL:         service.GetSetting(service.SettingTelegramAPIURL),
+		TelegramBotEnabled:     service.GetSetting(service.SettingTelegramBotEnabled),
+		TelegramAllowedChatIDs: service.GetSetting(service.SettingTelegramAllowedChatIDs),
+		PublicURL:              service.GetSetting(service.SettingPublicURL),
+		RemindOffsets:          service.GetSetting(model.SettingRemindOffsets),
+		Timezone:               service.GetSetting(model.SettingTimezone),
+		AutoRenewPolicy:        service.GetSetting(model.SettingAutoRenewPolicy),
+		NotifyMode:             service.GetSetting(service.SettingNotifyMode),
+		DigestCadence:          service.GetSetting(service.SettingDigestCadence),
+		DigestSchedule:         service.EffectiveDigestSchedule(service.GetSetting(service.SettingDigestSchedule), service.GetSetting(service.SettingDigestCadence)),
+		DigestDays:             service.GetSetting(service.SettingDigestDays),
+
+		OverdueRemindInterval:         service.GetSetting(model.SettingOverdueInterval),
+		OverdueRemindMax:              service.GetSetting(model.SettingOverdueMax),
+		OverdueEscalateAfter:          service.GetSetting(model.SettingOverdueEscalateAfter),
+		OverdueEscalateTelegramChatID: service.GetSetting(model.SettingOverdueEscalateTelegramChatID),
+		OverdueEscalateBarkURL:        service.GetSetting(model.SettingOverdueEscalateBarkURL),
 	}
-	
+
 	c.JSON(http.StatusOK, settings)
 }
 
-// UpdateSettings 更新设置
+// UpdateSettings 更新设置，校验失败时不保存任何设置项，并在 fields 中按设置项返回错误
 func UpdateSettings(c *gin.Context) {
 	var req UpdateSettingsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -50,20 +111,66 @@ func UpdateSettings(c *gin.Context) {
 		return
 	}
 
-	if req.NotifyHours != "" {
-		setSetting("notify_hours", req.NotifyHours)
+	if _, err := service.UpdateSettings(req); err != nil {
+		var fieldErrs service.SettingErrors
+		if errors.As(err, &fieldErrs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "设置校验失败", "fields": fieldErrs})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存设置失败"})
+		return
+	}
+
+	preview, _ := notifySchedulePreview(currentNotifySchedule(), service.GetSetting(service.SettingQuietHours), defaultPreviewCount)
+	c.JSON(http.StatusOK, gin.H{"message": "设置更新成功", "notify_preview": preview})
+}
+
+// PreviewNotifySchedule 校验通知计划与免打扰时段并预览之后的通知时间（按全局时区），不保存设置
+func PreviewNotifySchedule(c *gin.Context) {
+	var req NotifySchedulePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
 	}
-	if req.TelegramBotToken != "" {
-		setSetting("telegram_bot_token", req.TelegramBotToken)
+	if req.NotifySchedule == "" {
+		req.NotifySchedule = currentNotifySchedule()
 	}
-	if req.TelegramChatID != "" {
-		setSetting("telegram_chat_id", req.TelegramChatID)
+	if req.QuietHours == "" {
+		req.QuietHours = service.GetSetting(service.SettingQuietHours)
 	}
-	if req.BarkURL != "" {
-		setSetting("bark_url", req.BarkURL)
+	if req.Count == 0 {
+		req.Count = defaultPreviewCount
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "设置更新成功"})
+	preview, err := notifySchedulePreview(req.NotifySchedule, req.QuietHours, req.Count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notify_schedule": strings.TrimSpace(req.NotifySchedule),
+		"quiet_hours":     req.QuietHours,
+		"timezone":        model.GlobalLocation().String(),
+		"next":            preview,
+	})
+}
+
+// currentNotifySchedule 当前实际使用的通知计划
+func currentNotifySchedule() string {
+	return service.EffectiveNotifySchedule(service.GetSetting(service.SettingNotifySchedule), service.GetSetting(service.SettingNotifyHours))
+}
+
+// notifySchedulePreview 按全局时区预览之后 count 次通知时间
+func notifySchedulePreview(expr, quietHours string, count int) ([]time.Time, error) {
+	schedule, err := service.ParseNotifySchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+	quiet, err := service.ParseQuietHours(quietHours)
+	if err != nil {
+		return nil, err
+	}
+	return service.NextNotifyTimes(schedule, quiet, clock.Now().In(model.GlobalLocation()), count), nil
 }
 
 // TestNotify 测试通知
@@ -75,26 +182,26 @@ func TestNotify(c *gin.Context) {
 	}
 
 	notifier := service.NewNotifier()
-	
+
 	testMsg := "SubDock 通知测试 - 如果你看到这条消息，说明通知配置正确！"
 	var err error
 
 	switch req.Type {
 	case "telegram":
-		token := getSetting("telegram_bot_token", "")
-		chatID := getSetting("telegram_chat_id", "")
+		token := service.GetSetting(service.SettingTelegramBotToken)
+		chatID := service.GetSetting(service.SettingTelegramChatID)
 		if token == "" || chatID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "请先配置 Telegram Bot Token 和 Chat ID"})
 			return
 		}
-		err = notifier.SendTelegram(token, chatID, testMsg)
+		err = notifier.SendTelegram(service.GetSetting(service.SettingTelegramAPIURL), token, chatID, testMsg, nil)
 	case "bark":
-		barkURL := getSetting("bark_url", "")
+		barkURL := service.GetSetting(service.SettingBarkURL)
 		if barkURL == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "请先配置 Bark URL"})
 			return
 		}
-		err = notifier.SendBark(barkURL, "SubDock 通知测试", testMsg)
+		err = notifier.SendBark(barkURL, "SubDock 通知测试", testMsg, service.NotifyEventTest)
 	}
 
 	if err != nil {
@@ -104,21 +211,3 @@ func TestNotify(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "测试通知已发送"})
 }
-
-func getSetting(key, defaultVal string) string {
-	var setting model.Setting
-	if err := model.GetDB().Where("key = ?", key).First(&setting).Error; err != nil {
-		return defaultVal
-	}
-	return setting.Value
-}
-
-func setSetting(key, value string) {
-	var setting model.Setting
-	result := model.GetDB().Where("key = ?", key).First(&setting)
-	if result.Error != nil {
-		model.GetDB().Create(&model.Setting{Key: key, Value: value})
-	} else {
-		model.GetDB().Model(&setting).Update("value", value)
-	}
-}
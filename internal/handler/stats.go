@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"subdock/internal/service"
+)
+
+// GetStats 获取费用统计
+// 查询参数：currency 汇总币种（默认为基准币种），exclude_zero 排除免费订阅，top 排行数量（默认 5）
+func GetStats(c *gin.Context) {
+	opts := service.StatsOptions{
+		Currency:    c.DefaultQuery("currency", service.GetSetting(service.SettingBaseCurrency)),
+		ExcludeZero: c.Query("exclude_zero") == "true" || c.Query("exclude_zero") == "1",
+		Top:         5,
+	}
+	if top := c.Query("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top 参数无效"})
+			return
+		}
+		opts.Top = n
+	}
+
+	stats, err := service.BuildStats(opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetForecast 获取未来扣费预测
+// 查询参数：months 预测月数（默认 12，最多 60），currency 汇总币种（默认为基准币种）
+func GetForecast(c *gin.Context) {
+	months := 12
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 60 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months 参数应为 1-60"})
+			return
+		}
+		months = n
+	}
+
+	forecast, err := service.BuildForecast(months, c.DefaultQuery("currency", service.GetSetting(service.SettingBaseCurrency)), userLocation(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "预测失败"})
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
@@ -1,52 +1,105 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm/clause"
+	"gorm.io/gorm"
 
+	"subdock/internal/clock"
 	"subdock/internal/model"
 	"subdock/internal/service"
 )
 
 // CreateSubscriptionRequest 创建订阅请求
 type CreateSubscriptionRequest struct {
-	Name       string  `json:"name" binding:"required"`
-	Amount     float64 `json:"amount" binding:"gte=0"`
-	Currency   string  `json:"currency"`
-	StartDate  string  `json:"start_date" binding:"required"`
-	CycleValue int     `json:"cycle_value" binding:"required,gt=0"`
-	CycleUnit  string  `json:"cycle_unit" binding:"required,oneof=day month quarter half_year year"`
-	ExpireDate string  `json:"expire_date"`
-	AutoRenew  bool    `json:"auto_renew"`
-	RemindDays int     `json:"remind_days"`
-	Remark     string  `json:"remark"`
+	Name           string   `json:"name" binding:"required"`
+	Amount         float64  `json:"amount" binding:"gte=0"`
+	Currency       string   `json:"currency"`
+	StartDate      string   `json:"start_date" binding:"required"`
+	BillingType    string   `json:"billing_type" binding:"omitempty,oneof=recurring one_time lifetime"` // 为空表示周期订阅
+	CycleValue     int      `json:"cycle_value" binding:"omitempty,gt=0"`                               // 周期订阅必填
+	CycleUnit      string   `json:"cycle_unit" binding:"omitempty,oneof=day week business_day month quarter half_year year month_days rrule"`
+	CycleRule      string   `json:"cycle_rule"`       // month_days 如 "15,last"，rrule 如 "FREQ=MONTHLY;BYDAY=-1FR"
+	ExpireDate     string   `json:"expire_date"`      // 一次性购买为空表示不会到期
+	SupportEndDate string   `json:"support_end_date"` // 支持/更新截止日期
+	AutoRenew      bool     `json:"auto_renew"`
+	AnchorDay      int      `json:"anchor_day" binding:"omitempty,min=1,max=31"`
+	RemindOffsets  string   `json:"remind_offsets"` // 为空使用全局默认
+	RemindDays     int      `json:"remind_days"`    // 旧版提前提醒天数，未提供 remind_offsets 时换算
+	Timezone       string   `json:"timezone"`       // IANA 时区名称，为空使用全局时区
+	Status         string   `json:"status" binding:"omitempty,oneof=active paused cancelled"`
+	CategoryID     *uint    `json:"category_id"`
+	Tags           []string `json:"tags"`
+	Remark         string   `json:"remark"`
 }
 
 // UpdateSubscriptionRequest 更新订阅请求
 type UpdateSubscriptionRequest struct {
-	Name       string   `json:"name"`
-	Amount     *float64 `json:"amount"`
-	Currency   string   `json:"currency"`
-	StartDate  string   `json:"start_date"`
-	CycleValue int      `json:"cycle_value"`
-	CycleUnit  string   `json:"cycle_unit"`
-	ExpireDate string   `json:"expire_date"`
-	AutoRenew  *bool    `json:"auto_renew"`
-	RemindDays int      `json:"remind_days"`
-	Remark     string   `json:"remark"`
+	Name           string   `json:"name"`
+	Amount         *float64 `json:"amount"`
+	Currency       string   `json:"currency"`
+	StartDate      string   `json:"start_date"`
+	BillingType    string   `json:"billing_type" binding:"omitempty,oneof=recurring one_time lifetime"`
+	CycleValue     int      `json:"cycle_value"`
+	CycleUnit      string   `json:"cycle_unit" binding:"omitempty,oneof=day week business_day month quarter half_year year month_days rrule"`
+	CycleRule      *string  `json:"cycle_rule"`
+	ExpireDate     string   `json:"expire_date"`      // 仅对一次性购买生效
+	SupportEndDate *string  `json:"support_end_date"` // 传空字符串表示清除
+	AutoRenew      *bool    `json:"auto_renew"`
+	AnchorDay      *int     `json:"anchor_day" binding:"omitempty,min=1,max=31"`
+	RemindOffsets  *string  `json:"remind_offsets"` // 传空字符串表示改用全局默认
+	RemindDays     int      `json:"remind_days"`    // 旧版提前提醒天数
+	Timezone       *string  `json:"timezone"`       // 传空字符串表示改用全局时区
+	Status         string   `json:"status" binding:"omitempty,oneof=active paused cancelled"`
+	CategoryID     *uint    `json:"category_id"` // 传 0 表示清除分类
+	Tags           []string `json:"tags"`        // 传空数组表示清除标签，不传则不修改
+	Remark         string   `json:"remark"`
 }
 
 // ListSubscriptions 获取订阅列表
+// 筛选参数见 applySubscriptionFilters，排序与分页参数见 parseSubscriptionListQuery；
+// 响应体始终为订阅数组，总数通过 X-Total-Count 响应头返回，游标分页的下一页游标通过 X-Next-Cursor 返回
 func ListSubscriptions(c *gin.Context) {
+	listQuery, err := parseSubscriptionListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered, err := applySubscriptionFilters(c, model.GetDB().Model(&model.Subscription{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅列表失败"})
+		return
+	}
+
+	query, err := listQuery.apply(filtered.Session(&gorm.Session{}).Preload("Category").Preload("Tags"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var subscriptions []model.Subscription
-	if err := model.GetDB().Order("expire_date asc").Find(&subscriptions).Error; err != nil {
+	if err := query.Find(&subscriptions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取订阅列表失败"})
 		return
 	}
+
+	if listQuery.UseCursor && len(subscriptions) > listQuery.PageSize {
+		subscriptions = subscriptions[:listQuery.PageSize]
+		c.Header("X-Next-Cursor", listQuery.nextCursor(&subscriptions[len(subscriptions)-1]))
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
 	c.JSON(http.StatusOK, subscriptions)
 }
 
@@ -59,7 +112,7 @@ func GetSubscription(c *gin.Context) {
 	}
 
 	var subscription model.Subscription
-	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+	if err := subscriptionQuery().First(&subscription, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
 		return
 	}
@@ -85,25 +138,76 @@ func CreateSubscription(c *gin.Context) {
 		currency = "CNY"
 	}
 
-	remindDays := req.RemindDays
-	if remindDays <= 0 {
-		remindDays = 3
+	billingType := model.BillingType(req.BillingType)
+	if billingType == "" {
+		billingType = model.BillingRecurring
+	}
+	cycleRule := strings.TrimSpace(req.CycleRule)
+	if billingType == model.BillingRecurring {
+		if req.CycleValue == 0 || req.CycleUnit == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "周期订阅需要提供 cycle_value 和 cycle_unit"})
+			return
+		}
+		if err := model.ValidateCycle(model.CycleUnit(req.CycleUnit), cycleRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if req.AutoRenew {
+		c.JSON(http.StatusBadRequest, gin.H{"error": model.ErrNotRenewable.Error()})
+		return
+	}
+
+	supportEndDate, err := parseOptionalDate(req.SupportEndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "支持截止日期格式错误，应为 YYYY-MM-DD"})
+		return
+	}
+
+	remindOffsets, _, err := resolveRemindOffsets(&req.RemindOffsets, req.RemindDays)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	timezone := strings.TrimSpace(req.Timezone)
+	if err := model.ValidateTimezone(timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := model.SubscriptionStatus(req.Status)
+	if status == "" {
+		status = model.StatusActive
+	}
+
+	anchorDay := req.AnchorDay
+	if anchorDay == 0 {
+		anchorDay = startDate.Day()
 	}
 
 	subscription := &model.Subscription{
-		Name:       req.Name,
-		Amount:     req.Amount,
-		Currency:   currency,
-		StartDate:  startDate,
-		CycleValue: req.CycleValue,
-		CycleUnit:  model.CycleUnit(req.CycleUnit),
-		AutoRenew:  req.AutoRenew,
-		RemindDays: remindDays,
-		Remark:     req.Remark,
-	}
-
-	// 计算到期日期
-	if req.ExpireDate != "" {
+		Name:          req.Name,
+		Amount:        req.Amount,
+		Currency:      currency,
+		StartDate:     startDate,
+		BillingType:   billingType,
+		CycleValue:    req.CycleValue,
+		CycleUnit:     model.CycleUnit(req.CycleUnit),
+		CycleRule:     cycleRule,
+		AutoRenew:     req.AutoRenew,
+		AnchorDay:     anchorDay,
+		RemindOffsets: remindOffsets,
+		Timezone:      timezone,
+		Status:        status,
+		Remark:        req.Remark,
+
+		SupportEndDate: supportEndDate,
+	}
+
+	// 计算到期日期：永久授权不会到期，一次性购买未提供到期日期时同样不会到期
+	if billingType == model.BillingLifetime || (billingType == model.BillingOneTime && req.ExpireDate == "") {
+		subscription.ExpireDate = model.NoExpireDate
+	} else if req.ExpireDate != "" {
 		expireDate, err := time.Parse("2006-01-02", req.ExpireDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "到期日期格式错误，应为 YYYY-MM-DD"})
@@ -114,11 +218,28 @@ func CreateSubscription(c *gin.Context) {
 		subscription.ExpireDate = subscription.CalculateExpireDate()
 	}
 
-	if err := model.GetDB().Create(subscription).Error; err != nil {
+	if req.CategoryID != nil && *req.CategoryID > 0 {
+		if !validateCategoryID(*req.CategoryID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			return
+		}
+		subscription.CategoryID = req.CategoryID
+	}
+
+	err = model.GetDB().Transaction(func(tx *gorm.DB) error {
+		tags, err := resolveTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+		subscription.Tags = tags
+		return tx.Create(subscription).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建订阅失败"})
 		return
 	}
 
+	subscriptionQuery().First(subscription, subscription.ID)
 	c.JSON(http.StatusCreated, subscription)
 }
 
@@ -144,6 +265,7 @@ func UpdateSubscription(c *gin.Context) {
 
 	updates := make(map[string]interface{})
 	cycleRelatedChanged := false
+	wasRecurring := subscription.IsRecurring()
 
 	if req.Name != "" {
 		updates["name"] = req.Name
@@ -166,6 +288,15 @@ func UpdateSubscription(c *gin.Context) {
 		}
 		updates["start_date"] = startDate
 		subscription.StartDate = startDate
+		if req.AnchorDay == nil {
+			updates["anchor_day"] = startDate.Day()
+			subscription.AnchorDay = startDate.Day()
+		}
+		cycleRelatedChanged = true
+	}
+	if req.AnchorDay != nil {
+		updates["anchor_day"] = *req.AnchorDay
+		subscription.AnchorDay = *req.AnchorDay
 		cycleRelatedChanged = true
 	}
 	if req.CycleValue > 0 {
@@ -178,26 +309,108 @@ func UpdateSubscription(c *gin.Context) {
 		subscription.CycleUnit = model.CycleUnit(req.CycleUnit)
 		cycleRelatedChanged = true
 	}
-	if req.AutoRenew != nil {
+	if req.CycleRule != nil {
+		updates["cycle_rule"] = strings.TrimSpace(*req.CycleRule)
+		subscription.CycleRule = strings.TrimSpace(*req.CycleRule)
+		cycleRelatedChanged = true
+	}
+	if req.BillingType != "" {
+		updates["billing_type"] = req.BillingType
+		subscription.BillingType = model.BillingType(req.BillingType)
+		// 改回周期订阅时按周期重新计算到期日期
+		if subscription.IsRecurring() && !wasRecurring {
+			cycleRelatedChanged = true
+		}
+	}
+	if !subscription.IsRecurring() {
+		cycleRelatedChanged = false
+		if req.AutoRenew != nil && *req.AutoRenew {
+			c.JSON(http.StatusBadRequest, gin.H{"error": model.ErrNotRenewable.Error()})
+			return
+		}
+		updates["auto_renew"] = false
+		if subscription.BillingType == model.BillingLifetime {
+			updates["expire_date"] = model.NoExpireDate
+		} else if req.ExpireDate != "" {
+			expireDate, err := time.Parse("2006-01-02", req.ExpireDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "到期日期格式错误，应为 YYYY-MM-DD"})
+				return
+			}
+			updates["expire_date"] = expireDate
+		}
+	}
+	if cycleRelatedChanged {
+		if err := model.ValidateCycle(subscription.CycleUnit, subscription.CycleRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.AutoRenew != nil && subscription.IsRecurring() {
 		updates["auto_renew"] = *req.AutoRenew
 	}
-	if req.RemindDays > 0 {
-		updates["remind_days"] = req.RemindDays
+	if req.SupportEndDate != nil {
+		supportEndDate, err := parseOptionalDate(*req.SupportEndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "支持截止日期格式错误，应为 YYYY-MM-DD"})
+			return
+		}
+		updates["support_end_date"] = supportEndDate
+	}
+	if remindOffsets, ok, err := resolveRemindOffsets(req.RemindOffsets, req.RemindDays); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if ok {
+		updates["remind_offsets"] = remindOffsets
+	}
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if err := model.ValidateTimezone(timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["timezone"] = timezone
+	}
+	if req.Status != "" {
+		updates["status"] = req.Status
 	}
 	if req.Remark != "" {
 		updates["remark"] = req.Remark
 	}
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			updates["category_id"] = nil
+		} else if !validateCategoryID(*req.CategoryID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "分类不存在"})
+			return
+		} else {
+			updates["category_id"] = *req.CategoryID
+		}
+	}
 
 	if cycleRelatedChanged {
 		updates["expire_date"] = subscription.CalculateExpireDate()
 	}
 
-	if err := model.GetDB().Model(&subscription).Updates(updates).Error; err != nil {
+	err = model.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&subscription).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Tags == nil {
+			return nil
+		}
+		tags, err := resolveTags(tx, req.Tags)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&subscription).Association("Tags").Replace(tags)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新订阅失败"})
 		return
 	}
 
-	model.GetDB().First(&subscription, id)
+	subscriptionQuery().First(&subscription, id)
 	c.JSON(http.StatusOK, subscription)
 }
 
@@ -209,55 +422,21 @@ func RenewSubscription(c *gin.Context) {
 		return
 	}
 
-	tx := model.GetDB().Begin()
-	if tx.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "续订失败"})
-		return
-	}
-
-	var subscription model.Subscription
-	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&subscription, id).Error; err != nil {
-		tx.Rollback()
+	subscription, err := service.RenewSubscription(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "订阅不存在"})
 		return
 	}
-
-	oldExpireDate := subscription.ExpireDate
-	base := subscription.ExpireDate
-	if subscription.CycleValue <= 0 {
-		subscription.CycleValue = 1
-	}
-	newExpireDate := subscription.CalculateExpireDateFrom(base)
-	newRenewCount := subscription.RenewCount + 1
-
-	if err := tx.Model(&subscription).Updates(map[string]interface{}{
-		"expire_date": newExpireDate,
-		"renew_count": newRenewCount,
-	}).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "续订失败"})
-		return
-	}
-
-	renewal := &model.SubscriptionRenewal{
-		SubscriptionID: subscription.ID,
-		RenewedAt:      time.Now(),
-		OldExpireDate:  oldExpireDate,
-		NewExpireDate:  newExpireDate,
-		RenewCount:     newRenewCount,
-	}
-	if err := tx.Create(renewal).Error; err != nil {
-		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "续订记录写入失败"})
+	if errors.Is(err, model.ErrCycleExhausted) || errors.Is(err, model.ErrNotRenewable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	if err := tx.Commit().Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "续订失败"})
 		return
 	}
 
-	model.GetDB().First(&subscription, id)
+	subscriptionQuery().First(subscription, id)
 	c.JSON(http.StatusOK, subscription)
 }
 
@@ -291,19 +470,19 @@ func TestSubscriptionNotify(c *gin.Context) {
 		return
 	}
 
-	var telegramBotToken, telegramChatID, barkURL string
-	model.GetDB().Model(&model.Setting{}).Where("key = ?", "telegram_bot_token").Pluck("value", &telegramBotToken)
-	model.GetDB().Model(&model.Setting{}).Where("key = ?", "telegram_chat_id").Pluck("value", &telegramChatID)
-	model.GetDB().Model(&model.Setting{}).Where("key = ?", "bark_url").Pluck("value", &barkURL)
+	telegramBotToken := service.GetSetting(service.SettingTelegramBotToken)
+	telegramChatID := service.GetSetting(service.SettingTelegramChatID)
+	barkURL := service.GetSetting(service.SettingBarkURL)
 
-	msg := formatSubscriptionNotification(&subscription)
+	msg := formatSubscriptionNotification(&subscription) +
+		service.ReminderLinksText(service.GetSetting(service.SettingPublicURL), subscription.ID, clock.Now())
 
 	notifier := service.NewNotifier()
 	var sent bool
 	var errMsg string
 
 	if telegramBotToken != "" && telegramChatID != "" {
-		if err := notifier.SendTelegram(telegramBotToken, telegramChatID, msg); err != nil {
+		if err := notifier.SendTelegram(service.GetSetting(service.SettingTelegramAPIURL), telegramBotToken, telegramChatID, msg, service.ReminderKeyboard(subscription.ID)); err != nil {
 			errMsg += "Telegram: " + err.Error() + "; "
 		} else {
 			sent = true
@@ -311,7 +490,7 @@ func TestSubscriptionNotify(c *gin.Context) {
 	}
 
 	if barkURL != "" {
-		if err := notifier.SendBark(barkURL, "SubDock 订阅提醒", msg); err != nil {
+		if err := notifier.SendBark(barkURL, "SubDock 订阅提醒", msg, service.NotifyEventTest); err != nil {
 			errMsg += "Bark: " + err.Error()
 		} else {
 			sent = true
@@ -329,16 +508,47 @@ func TestSubscriptionNotify(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "通知发送成功"})
 }
 
+// subscriptionQuery 订阅查询，预加载分类和标签
+func subscriptionQuery() *gorm.DB {
+	return model.GetDB().Preload("Category").Preload("Tags")
+}
+
 // formatSubscriptionNotification 格式化订阅通知消息
 func formatSubscriptionNotification(sub *model.Subscription) string {
 	return "📋 订阅提醒测试\n\n" +
 		"名称：" + sub.Name + "\n" +
 		"金额：" + sub.Currency + " " + formatFloat(sub.Amount) + "\n" +
 		"开始日期：" + sub.StartDate.Format("2006-01-02") + "\n" +
-		"到期日期：" + sub.ExpireDate.Format("2006-01-02") + "\n" +
+		"到期日期：" + sub.ExpireDateText() + "\n" +
 		"备注：" + sub.Remark
 }
 
+// parseOptionalDate 解析可选的 YYYY-MM-DD 日期，空字符串返回 nil
+func parseOptionalDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// resolveRemindOffsets 解析请求中的提醒偏移，兼容旧版 remind_days
+// ok 为 false 表示请求未涉及提醒设置
+func resolveRemindOffsets(offsets *string, legacyDays int) (value string, ok bool, err error) {
+	if offsets != nil && (*offsets != "" || legacyDays <= 0) {
+		value, err = model.NormalizeRemindOffsets(*offsets)
+		return value, true, err
+	}
+	if legacyDays > 0 {
+		return model.LegacyRemindOffsets(legacyDays), true, nil
+	}
+	return "", false, nil
+}
+
 func formatFloat(f float64) string {
 	return strconv.FormatFloat(f, 'f', 2, 64)
 }
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"subdock/internal/model"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// sortableFields 允许排序的字段
+var sortableFields = map[string]bool{
+	"expire_date": true,
+	"start_date":  true,
+	"name":        true,
+	"amount":      true,
+	"created_at":  true,
+	"id":          true,
+}
+
+// timeSortFields 按时间排序的字段
+// SQLite 以文本保存时间，不同记录可能带不同的时区偏移，排序和游标比较统一换算为 UTC 毫秒精度的文本
+var timeSortFields = map[string]bool{
+	"expire_date": true,
+	"start_date":  true,
+	"created_at":  true,
+}
+
+// cursorTimeLayout 游标中时间排序值的格式，与 SQLite strftime('%Y-%m-%d %H:%M:%f') 的结果一致
+const cursorTimeLayout = "2006-01-02 15:04:05.000"
+
+// subscriptionListQuery 订阅列表查询参数
+type subscriptionListQuery struct {
+	Sort      string
+	Desc      bool
+	Page      int    // 偏移分页页码，从 1 开始；0 表示不使用偏移分页
+	PageSize  int    // 每页数量
+	Cursor    string // 游标分页，传空字符串开始第一页
+	UseCursor bool
+}
+
+// listCursor 游标内容：上一页最后一条记录的排序字段值和 ID
+type listCursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// applySubscriptionFilters 根据查询参数添加筛选条件
+// 支持：q 名称/备注模糊搜索，status、currency（逗号分隔多个），category_id（none 表示未分类），tag，
+// amount_min/amount_max，expire_from/expire_to（YYYY-MM-DD，含边界），auto_renew（true/false）
+func applySubscriptionFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + escapeLike(q) + "%"
+		query = query.Where("(name LIKE ? ESCAPE '\\' OR remark LIKE ? ESCAPE '\\')", like, like)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status IN ?", splitList(status))
+	}
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("UPPER(currency) IN ?", splitList(strings.ToUpper(currency)))
+	}
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		if categoryID == "none" {
+			query = query.Where("category_id IS NULL")
+		} else {
+			id, err := strconv.ParseUint(categoryID, 10, 32)
+			if err != nil {
+				return nil, errors.New("无效的分类 ID")
+			}
+			query = query.Where("category_id = ?", id)
+		}
+	}
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Where("id IN (?)", model.GetDB().Table("subscription_tags").
+			Select("subscription_tags.subscription_id").
+			Joins("JOIN tags ON tags.id = subscription_tags.tag_id").
+			Where("tags.name IN ?", splitList(tag)))
+	}
+	if v := c.Query("amount_min"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, errors.New("amount_min 参数无效")
+		}
+		query = query.Where("amount >= ?", amount)
+	}
+	if v := c.Query("amount_max"); v != "" {
+		amount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, errors.New("amount_max 参数无效")
+		}
+		query = query.Where("amount <= ?", amount)
+	}
+	if v := c.Query("expire_from"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, errors.New("expire_from 格式错误，应为 YYYY-MM-DD")
+		}
+		query = query.Where("expire_date >= ?", date)
+	}
+	if v := c.Query("expire_to"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, errors.New("expire_to 格式错误，应为 YYYY-MM-DD")
+		}
+		query = query.Where("expire_date < ?", date.AddDate(0, 0, 1))
+	}
+	if v := c.Query("auto_renew"); v != "" {
+		autoRenew, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("auto_renew 参数无效")
+		}
+		query = query.Where("auto_renew = ?", autoRenew)
+	}
+	return query, nil
+}
+
+// parseSubscriptionListQuery 解析排序与分页参数
+// sort 排序字段，order 为 asc/desc；page/page_size 偏移分页；cursor/limit 游标分页
+func parseSubscriptionListQuery(c *gin.Context) (*subscriptionListQuery, error) {
+	q := &subscriptionListQuery{Sort: c.DefaultQuery("sort", "expire_date")}
+	if !sortableFields[q.Sort] {
+		return nil, errors.New("不支持的排序字段: " + q.Sort)
+	}
+
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return nil, errors.New("order 参数应为 asc 或 desc")
+	}
+
+	_, hasCursor := c.GetQuery("cursor")
+	_, hasLimit := c.GetQuery("limit")
+	_, hasPage := c.GetQuery("page")
+	_, hasPageSize := c.GetQuery("page_size")
+	if (hasCursor || hasLimit) && (hasPage || hasPageSize) {
+		return nil, errors.New("cursor/limit 与 page/page_size 不能同时使用")
+	}
+
+	sizeParam := "page_size"
+	if hasCursor || hasLimit {
+		q.UseCursor = true
+		q.Cursor = c.Query("cursor")
+		sizeParam = "limit"
+	}
+
+	if hasPage || hasPageSize || q.UseCursor {
+		q.PageSize = defaultPageSize
+		if v := c.Query(sizeParam); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxPageSize {
+				return nil, errors.New(sizeParam + " 参数应为 1-" + strconv.Itoa(maxPageSize))
+			}
+			q.PageSize = n
+		}
+	}
+	if !q.UseCursor && q.PageSize > 0 {
+		q.Page = 1
+		if v := c.Query("page"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, errors.New("page 参数无效")
+			}
+			q.Page = n
+		}
+	}
+	return q, nil
+}
+
+// apply 添加排序与分页条件，ID 作为次级排序保证结果稳定
+func (q *subscriptionListQuery) apply(query *gorm.DB) (*gorm.DB, error) {
+	direction, cmp := "asc", ">"
+	if q.Desc {
+		direction, cmp = "desc", "<"
+	}
+
+	if q.UseCursor && q.Cursor != "" {
+		cursor, value, err := q.decodeCursor()
+		if err != nil {
+			return nil, err
+		}
+		if q.Sort == "id" {
+			query = query.Where("id "+cmp+" ?", cursor.ID)
+		} else {
+			column := q.sortColumn()
+			query = query.Where("("+column+" "+cmp+" ? OR ("+column+" = ? AND id "+cmp+" ?))", value, value, cursor.ID)
+		}
+	}
+
+	query = query.Order(q.sortColumn() + " " + direction)
+	if q.Sort != "id" {
+		query = query.Order("id " + direction)
+	}
+
+	switch {
+	case q.UseCursor:
+		// 多取一条用于判断是否还有下一页
+		query = query.Limit(q.PageSize + 1)
+	case q.Page > 0:
+		query = query.Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize)
+	}
+	return query, nil
+}
+
+// sortColumn 排序使用的 SQL 表达式，时间字段换算为 UTC 文本后比较
+func (q *subscriptionListQuery) sortColumn() string {
+	if timeSortFields[q.Sort] {
+		return "strftime('%Y-%m-%d %H:%M:%f', " + q.Sort + ")"
+	}
+	return q.Sort
+}
+
+// cursorTime 将时间排序值转换为游标格式，与 SQLite 一样四舍五入到毫秒
+func cursorTime(t time.Time) string {
+	return t.UTC().Round(time.Millisecond).Format(cursorTimeLayout)
+}
+
+// nextCursor 根据本页最后一条记录生成下一页游标
+func (q *subscriptionListQuery) nextCursor(last *model.Subscription) string {
+	var value interface{}
+	switch q.Sort {
+	case "expire_date":
+		value = cursorTime(last.ExpireDate)
+	case "start_date":
+		value = cursorTime(last.StartDate)
+	case "created_at":
+		value = cursorTime(last.CreatedAt)
+	case "name":
+		value = last.Name
+	case "amount":
+		value = last.Amount
+	}
+
+	raw, _ := json.Marshal(value)
+	data, _ := json.Marshal(listCursor{Value: raw, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标，并按排序字段类型还原排序值
+func (q *subscriptionListQuery) decodeCursor() (*listCursor, interface{}, error) {
+	invalid := errors.New("无效的游标")
+
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, nil, invalid
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, nil, invalid
+	}
+
+	var value interface{}
+	switch q.Sort {
+	case "expire_date", "start_date", "created_at":
+		var s string
+		if err = json.Unmarshal(cursor.Value, &s); err == nil {
+			_, err = time.Parse(cursorTimeLayout, s)
+		}
+		value = s
+	case "name":
+		var s string
+		err = json.Unmarshal(cursor.Value, &s)
+		value = s
+	case "amount":
+		var f float64
+		err = json.Unmarshal(cursor.Value, &f)
+		value = f
+	}
+	if err != nil {
+		return nil, nil, invalid
+	}
+	return &cursor, value, nil
+}
+
+// splitList 拆分逗号分隔的参数
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"subdock/internal/config"
+	"subdock/internal/model"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "subdock-handler-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("DATA_DIR", dir)
+	config.Load()
+	log.SetOutput(io.Discard)
+	gin.SetMode(gin.TestMode)
+	if _, err := model.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// listPage 请求一页订阅列表，返回订阅 ID 与下一页游标
+func listPage(t *testing.T, params url.Values) ([]uint, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/subscriptions?"+params.Encode(), nil)
+	ListSubscriptions(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var subs []model.Subscription
+	if err := json.Unmarshal(w.Body.Bytes(), &subs); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	return ids, w.Header().Get("X-Next-Cursor")
+}
+
+func TestListSubscriptionsCursorEqualSortKeys(t *testing.T) {
+	db := model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true})
+	db.Exec("DELETE FROM subscriptions")
+	t.Cleanup(func() { db.Exec("DELETE FROM subscriptions") })
+
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	berlin := time.FixedZone("UTC+2", 2*3600)
+	// 到期日期全部相同；创建时间以不同时区偏移保存，其中前两条为同一时刻
+	createdAt := []time.Time{
+		time.Date(2026, 3, 1, 8, 0, 0, 0, shanghai),
+		time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 1, 1, 0, 0, 0, berlin),
+		time.Date(2026, 2, 28, 23, 30, 0, 0, time.UTC),
+		time.Date(2026, 3, 1, 0, 0, 0, 0, berlin),
+	}
+	ids := make([]uint, len(createdAt))
+	for i, at := range createdAt {
+		sub := model.Subscription{
+			Name:       "sub",
+			Currency:   "CNY",
+			StartDate:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			CycleValue: 1,
+			CycleUnit:  model.CycleUnitMonth,
+			ExpireDate: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+			Status:     model.StatusActive,
+			CreatedAt:  at,
+		}
+		if err := model.GetDB().Create(&sub).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = sub.ID
+	}
+
+	tests := []struct {
+		sort  string
+		order string
+		want  []uint
+	}{
+		{"expire_date", "asc", []uint{ids[0], ids[1], ids[2], ids[3], ids[4]}},
+		{"expire_date", "desc", []uint{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		// 按 UTC 时刻排序：2/28 22:00、23:00、23:30，3/1 00:00 两条按 ID
+		{"created_at", "asc", []uint{ids[4], ids[2], ids[3], ids[0], ids[1]}},
+		{"created_at", "desc", []uint{ids[1], ids[0], ids[3], ids[2], ids[4]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort+" "+tt.order, func(t *testing.T) {
+			var got []uint
+			cursor := ""
+			for page := 0; page < len(ids); page++ {
+				params := url.Values{"sort": {tt.sort}, "order": {tt.order}, "limit": {"2"}, "cursor": {cursor}}
+				pageIDs, next := listPage(t, params)
+				got = append(got, pageIDs...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, _, err := (&subscriptionListQuery{Sort: "created_at", Cursor: "bm90LWpzb24"}).decodeCursor(); err == nil {
+		t.Error("无效的游标应返回错误")
+	}
+}
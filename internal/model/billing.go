@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BillingType 计费类型
+type BillingType string
+
+const (
+	BillingRecurring BillingType = "recurring" // 周期订阅，按周期续订
+	BillingOneTime   BillingType = "one_time"  // 一次性购买，可有一个到期日期（如域名转入），不续订
+	BillingLifetime  BillingType = "lifetime"  // 永久授权，不会到期
+)
+
+// NoExpireDate 不会到期的订阅使用的到期日期，按到期日期排序时排在最后，也不会命中到期提醒
+var NoExpireDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// ErrNotRenewable 一次性购买或永久授权无需续订
+var ErrNotRenewable = errors.New("一次性购买或永久授权无需续订")
+
+// ValidateBillingType 校验计费类型，空字符串视为周期订阅
+func ValidateBillingType(t BillingType) error {
+	switch t {
+	case "", BillingRecurring, BillingOneTime, BillingLifetime:
+		return nil
+	}
+	return fmt.Errorf("无效的计费类型: %s", t)
+}
+
+// IsRecurring 是否为周期订阅
+func (s *Subscription) IsRecurring() bool {
+	return s.BillingType == "" || s.BillingType == BillingRecurring
+}
+
+// HasExpiry 是否有到期日期
+func (s *Subscription) HasExpiry() bool {
+	return s.ExpireDate.Year() < NoExpireDate.Year()
+}
+
+// ExpireDateText 到期日期文本，不会到期时为“永久有效”
+func (s *Subscription) ExpireDateText() string {
+	if !s.HasExpiry() {
+		return "永久有效"
+	}
+	return s.ExpireDate.Format("2006-01-02")
+}
+
+// ReminderDates 需要提醒的日期，按时间升序：到期日期与支持/更新截止日期
+func (s *Subscription) ReminderDates() []time.Time {
+	var dates []time.Time
+	if s.HasExpiry() {
+		dates = append(dates, CalendarDate(s.ExpireDate))
+	}
+	if s.SupportEndDate != nil {
+		support := CalendarDate(*s.SupportEndDate)
+		if len(dates) == 0 || !dates[0].Equal(support) {
+			dates = append(dates, support)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// IsSupportEnd 判断 date 是否为支持/更新截止日期而非到期日期
+func (s *Subscription) IsSupportEnd(date time.Time) bool {
+	if s.HasExpiry() && CalendarDate(s.ExpireDate).Equal(CalendarDate(date)) {
+		return false
+	}
+	return s.SupportEndDate != nil && CalendarDate(*s.SupportEndDate).Equal(CalendarDate(date))
+}
+
+// DueDate day 当天提醒所针对的日期：最后一次提醒尚未过去的最早日期，均已过去时取最晚的日期
+// 没有任何提醒日期时返回到期日期
+func (s *Subscription) DueDate(day time.Time) time.Time {
+	dates := s.ReminderDates()
+	if len(dates) == 0 {
+		return s.ExpireDate
+	}
+	day = CalendarDate(day)
+	offsets := s.EffectiveRemindOffsets()
+	trail := 0
+	if last := offsets[len(offsets)-1]; last < 0 {
+		trail = -last
+	}
+	for _, date := range dates {
+		if !day.After(date.AddDate(0, 0, trail)) {
+			return date
+		}
+	}
+	return dates[len(dates)-1]
+}
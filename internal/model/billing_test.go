@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+)
+
+func TestNonRecurringCost(t *testing.T) {
+	tests := []struct {
+		billing BillingType
+		want    float64
+	}{
+		{"", 10},
+		{BillingRecurring, 10},
+		{BillingOneTime, 0},
+		{BillingLifetime, 0},
+	}
+
+	for _, tt := range tests {
+		sub := Subscription{BillingType: tt.billing, Amount: 120, CycleValue: 1, CycleUnit: CycleUnitYear}
+		if got := sub.MonthlyCost(); got != tt.want {
+			t.Errorf("MonthlyCost(%q) = %v, want %v", tt.billing, got, tt.want)
+		}
+	}
+}
+
+func TestRenewNonRecurring(t *testing.T) {
+	sub := Subscription{BillingType: BillingLifetime, ExpireDate: NoExpireDate}
+	if _, err := sub.Renew(nil, sub.ExpireDate); !errors.Is(err, ErrNotRenewable) {
+		t.Errorf("Renew() error = %v, want ErrNotRenewable", err)
+	}
+}
+
+func TestReminderDates(t *testing.T) {
+	support := date("2026-06-30")
+	sameDay := date("2026-03-10")
+
+	tests := []struct {
+		name string
+		sub  Subscription
+		want []string
+	}{
+		{"周期订阅", Subscription{ExpireDate: date("2026-03-10")}, []string{"2026-03-10"}},
+		{"永久授权无支持截止", Subscription{BillingType: BillingLifetime, ExpireDate: NoExpireDate}, nil},
+		{"永久授权有支持截止", Subscription{BillingType: BillingLifetime, ExpireDate: NoExpireDate, SupportEndDate: &support}, []string{"2026-06-30"}},
+		{"支持截止早于到期", Subscription{BillingType: BillingOneTime, ExpireDate: date("2027-01-01"), SupportEndDate: &support}, []string{"2026-06-30", "2027-01-01"}},
+		{"支持截止与到期同一天", Subscription{BillingType: BillingOneTime, ExpireDate: date("2026-03-10"), SupportEndDate: &sameDay}, []string{"2026-03-10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.sub.ReminderDates()
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReminderDates() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("ReminderDates()[%d] = %s, want %s", i, got[i].Format("2006-01-02"), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	support := date("2026-06-30")
+	sub := Subscription{
+		BillingType:    BillingOneTime,
+		ExpireDate:     date("2027-01-01"),
+		SupportEndDate: &support,
+		RemindOffsets:  "7,0,-2",
+	}
+
+	tests := map[string]string{
+		"2026-06-01": "2026-06-30",
+		"2026-07-02": "2026-06-30", // 支持截止后的提醒尚未结束
+		"2026-07-03": "2027-01-01",
+		"2027-02-01": "2027-01-01",
+	}
+	for day, want := range tests {
+		if got := sub.DueDate(date(day)); !got.Equal(date(want)) {
+			t.Errorf("DueDate(%s) = %s, want %s", day, got.Format("2006-01-02"), want)
+		}
+	}
+}
+
+func TestShouldRemindSupportEnd(t *testing.T) {
+	support := date("2026-06-30")
+	sub := Subscription{
+		BillingType:    BillingLifetime,
+		ExpireDate:     NoExpireDate,
+		SupportEndDate: &support,
+		RemindOffsets:  "7,0",
+		Timezone:       "UTC",
+	}
+
+	fixed := clock.NewFixed(time.Date(2026, 6, 23, 9, 0, 0, 0, time.UTC))
+	t.Cleanup(clock.Set(fixed))
+
+	if !sub.ShouldRemindToday() {
+		t.Fatal("支持截止前 7 天应提醒")
+	}
+	if got, _, _ := sub.RemindTargetOn(sub.Today()); !sub.IsSupportEnd(got) {
+		t.Errorf("RemindTargetOn() = %s, want support end", got.Format("2006-01-02"))
+	}
+
+	acked := sub.DueDate(sub.Today())
+	sub.AckedExpire = &acked
+	if sub.ShouldRemindToday() {
+		t.Error("已确认的支持截止提醒不应再次提醒")
+	}
+
+	fixed.Set(time.Date(2099, 1, 1, 9, 0, 0, 0, time.UTC))
+	if sub.ShouldRemindToday() {
+		t.Error("永久授权不应有到期提醒")
+	}
+}
@@ -0,0 +1,477 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCycleExhausted 周期规则已没有后续日期（如 RRULE 的 COUNT/UNTIL 已用尽）
+var ErrCycleExhausted = errors.New("周期规则已无后续日期")
+
+// maxRuleScanDays 查找下一个规则日期时最多逐日检查的天数（约 100 年），超出后视为规则已用尽
+const maxRuleScanDays = 100 * 366
+
+// ruleProbeStart 校验 RRULE 能否命中时使用的起始日期（闰年元旦）
+var ruleProbeStart = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsRuleCycle 判断周期单位是否依赖 CycleRule
+func (u CycleUnit) IsRuleCycle() bool {
+	return u == CycleUnitMonthDays || u == CycleUnitRRule
+}
+
+// ValidateCycle 校验周期单位与周期规则
+func ValidateCycle(unit CycleUnit, rule string) error {
+	switch unit {
+	case CycleUnitMonthDays:
+		_, err := ParseMonthDays(rule)
+		return err
+	case CycleUnitRRule:
+		_, err := ParseRRule(rule)
+		return err
+	}
+	return nil
+}
+
+// NextCycleDate 计算从 base 起一个周期后的日期，规则已用尽时返回 false
+func (s *Subscription) NextCycleDate(base time.Time) (time.Time, bool) {
+	value := s.CycleValue
+	if value <= 0 {
+		value = 1
+	}
+	switch s.CycleUnit {
+	case CycleUnitDay:
+		return base.AddDate(0, 0, value), true
+	case CycleUnitWeek:
+		return base.AddDate(0, 0, value*7), true
+	case CycleUnitBusinessDay:
+		return addBusinessDays(base, value), true
+	case CycleUnitMonth:
+		return addMonths(base, value, s.BillingDay(base)), true
+	case CycleUnitQuarter:
+		return addMonths(base, value*3, s.BillingDay(base)), true
+	case CycleUnitHalfYear:
+		return addMonths(base, value*6, s.BillingDay(base)), true
+	case CycleUnitYear:
+		return addMonths(base, value*12, s.BillingDay(base)), true
+	case CycleUnitMonthDays:
+		days, err := ParseMonthDays(s.CycleRule)
+		if err != nil {
+			return base, false
+		}
+		next := base
+		for i := 0; i < value; i++ {
+			next = nextMonthDay(next, days)
+		}
+		return next, true
+	case CycleUnitRRule:
+		rule, err := ParseRRule(s.CycleRule)
+		if err != nil {
+			return base, false
+		}
+		return rule.Next(CalendarDate(s.StartDate), CalendarDate(base))
+	default:
+		return addMonths(base, value, s.BillingDay(base)), true
+	}
+}
+
+// cyclesPerYear 规则周期在开始日期之后一年内（含一年后当天）出现的次数
+func (s *Subscription) cyclesPerYear() int {
+	start := CalendarDate(s.StartDate)
+	end := start.AddDate(1, 0, 0)
+	count := 0
+	for next, ok := s.NextCycleDate(start); ok && !next.After(end) && count < 366; next, ok = s.NextCycleDate(next) {
+		count++
+	}
+	return count
+}
+
+// addBusinessDays 在 base 上增加 n 个工作日（周一至周五）
+func addBusinessDays(base time.Time, n int) time.Time {
+	for n > 0 {
+		base = base.AddDate(0, 0, 1)
+		if wd := base.Weekday(); wd != time.Saturday && wd != time.Sunday {
+			n--
+		}
+	}
+	return base
+}
+
+// ParseMonthDays 解析每月固定日期规则，如 "15,last"；last 或 -1 表示月末，超出当月天数的日期取月末
+func ParseMonthDays(rule string) ([]int, error) {
+	seen := make(map[int]bool)
+	var days []int
+	for _, p := range strings.Split(rule, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		day := -1
+		if p != "last" && p != "l" {
+			v, err := strconv.Atoi(p)
+			if err != nil || v == 0 || v < -1 || v > 31 {
+				return nil, fmt.Errorf("无效的每月日期: %s", p)
+			}
+			day = v
+		}
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return nil, errors.New("至少需要一个每月日期")
+	}
+	return days, nil
+}
+
+// nextMonthDay 返回 base 之后第一个匹配每月日期规则的日期
+func nextMonthDay(base time.Time, days []int) time.Time {
+	first := time.Date(base.Year(), base.Month(), 1, base.Hour(), base.Minute(), base.Second(), base.Nanosecond(), base.Location())
+	for i := 0; i < 3; i++ {
+		month := first.AddDate(0, i, 0)
+		last := daysInMonth(month.Year(), month.Month())
+		var candidates []int
+		for _, d := range days {
+			if d == -1 || d > last {
+				d = last
+			}
+			candidates = append(candidates, d)
+		}
+		sort.Ints(candidates)
+		for _, d := range candidates {
+			if date := month.AddDate(0, 0, d-1); date.After(base) {
+				return date
+			}
+		}
+	}
+	return base
+}
+
+// RRule RFC 5545 重复规则的常用子集：FREQ、INTERVAL、COUNT、UNTIL、BYDAY、BYMONTHDAY、BYMONTH、BYSETPOS
+// 仅按日期计算，忽略时分秒，周起始固定为周一
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time // 零值表示不限
+	ByDay      []RRuleWeekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+}
+
+// RRuleWeekday BYDAY 项，N 为序号（如 -1FR 表示最后一个周五），0 表示每个
+type RRuleWeekday struct {
+	N   int
+	Day time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRRule 解析 RRULE 字符串，可带 "RRULE:" 前缀
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "RRULE:"), "rrule:")
+	if s == "" {
+		return nil, errors.New("RRULE 不能为空")
+	}
+
+	rule := &RRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("无效的 RRULE 片段: %s", part)
+		}
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(value))
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rule.Freq = value
+			default:
+				return nil, fmt.Errorf("不支持的 FREQ: %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err != nil || rule.Interval <= 0 {
+				return nil, fmt.Errorf("无效的 INTERVAL: %s", value)
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err != nil || rule.Count <= 0 {
+				return nil, fmt.Errorf("无效的 COUNT: %s", value)
+			}
+		case "UNTIL":
+			rule.Until, err = parseRRuleDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 UNTIL: %s", value)
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("无效的 BYDAY: %s", v)
+				}
+				day, ok := rruleWeekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("无效的 BYDAY: %s", v)
+				}
+				n := 0
+				if prefix := v[:len(v)-2]; prefix != "" {
+					n, err = strconv.Atoi(prefix)
+					if err != nil || n == 0 || n < -53 || n > 53 {
+						return nil, fmt.Errorf("无效的 BYDAY: %s", v)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, RRuleWeekday{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRRuleInts(value, 1, 31)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 BYMONTHDAY: %s", value)
+			}
+		case "BYMONTH":
+			months, err := parseRRuleInts(value, 1, 12)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 BYMONTH: %s", value)
+			}
+			for _, m := range months {
+				if m < 0 {
+					return nil, fmt.Errorf("无效的 BYMONTH: %s", value)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			rule.BySetPos, err = parseRRuleInts(value, 1, 366)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 BYSETPOS: %s", value)
+			}
+		case "WKST":
+			if value != "MO" {
+				return nil, errors.New("WKST 仅支持 MO")
+			}
+		default:
+			return nil, fmt.Errorf("不支持的 RRULE 属性: %s", key)
+		}
+	}
+	if rule.Freq == "" {
+		return nil, errors.New("RRULE 缺少 FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, errors.New("COUNT 与 UNTIL 不能同时使用")
+	}
+	// 拒绝永远无法命中的规则（如 BYMONTH=2;BYMONTHDAY=30），避免查找时扫描到上限
+	probe := *rule
+	probe.Count, probe.Until = 0, time.Time{}
+	if _, ok := probe.Next(ruleProbeStart, ruleProbeStart.AddDate(0, 0, -1)); !ok {
+		return nil, errors.New("RRULE 没有任何匹配的日期")
+	}
+	return rule, nil
+}
+
+// parseRRuleInts 解析逗号分隔的整数列表，绝对值需在 [min, max] 内
+func parseRRuleInts(value string, min, max int) ([]int, error) {
+	var values []int
+	for _, p := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v == 0 || v < -max || v > max || (v > 0 && v < min) {
+			return nil, fmt.Errorf("无效的值: %s", p)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// parseRRuleDate 解析 UNTIL，支持 YYYYMMDD 与 YYYYMMDDTHHMMSS[Z]
+func parseRRuleDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, errors.New("日期格式错误")
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+// Next 返回以 dtstart 为起点的规则中晚于 after 的第一个日期，规则已用尽或 maxRuleScanDays 天内没有日期时返回 false
+func (r *RRule) Next(dtstart, after time.Time) (time.Time, bool) {
+	first := 0
+	// 未限制次数时直接从 after 附近的周期开始查找
+	if r.Count == 0 && after.After(dtstart) {
+		first = r.periodsBetween(dtstart, after)/r.Interval - 1
+		if first < 0 {
+			first = 0
+		}
+	}
+
+	seen, scanned := 0, 0
+	for k := first; scanned < maxRuleScanDays; k++ {
+		start, end := r.period(dtstart, k*r.Interval)
+		scanned += int(end.Sub(start).Hours()/24 + 0.5)
+		if !r.Until.IsZero() && start.After(r.Until) {
+			return time.Time{}, false
+		}
+		for _, date := range r.occurrences(dtstart, start, end) {
+			if date.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && date.After(r.Until) {
+				return time.Time{}, false
+			}
+			seen++
+			if r.Count > 0 && seen > r.Count {
+				return time.Time{}, false
+			}
+			if date.After(after) {
+				return date, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodsBetween dtstart 与 t 之间相隔的完整周期数（按 FREQ 单位）
+func (r *RRule) periodsBetween(dtstart, t time.Time) int {
+	switch r.Freq {
+	case "DAILY":
+		return int(t.Sub(dtstart).Hours() / 24)
+	case "WEEKLY":
+		return int(t.Sub(dtstart).Hours() / 24 / 7)
+	case "MONTHLY":
+		return (t.Year()-dtstart.Year())*12 + int(t.Month()-dtstart.Month())
+	default:
+		return t.Year() - dtstart.Year()
+	}
+}
+
+// period 返回第 n 个 FREQ 单位所在的日期区间 [start, end)
+func (r *RRule) period(dtstart time.Time, n int) (time.Time, time.Time) {
+	switch r.Freq {
+	case "DAILY":
+		start := dtstart.AddDate(0, 0, n)
+		return start, start.AddDate(0, 0, 1)
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) + 6) % 7 // 距离周一的天数
+		start := dtstart.AddDate(0, 0, n*7-offset)
+		return start, start.AddDate(0, 0, 7)
+	case "MONTHLY":
+		start := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n), 1, 0, 0, 0, 0, dtstart.Location())
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(dtstart.Year()+n, 1, 1, 0, 0, 0, 0, dtstart.Location())
+		return start, start.AddDate(1, 0, 0)
+	}
+}
+
+// occurrences 列出区间内满足规则的日期（升序），并应用 BYSETPOS
+func (r *RRule) occurrences(dtstart, start, end time.Time) []time.Time {
+	var dates []time.Time
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		if r.matches(dtstart, d) {
+			dates = append(dates, d)
+		}
+	}
+	if len(r.BySetPos) == 0 || len(dates) == 0 {
+		return dates
+	}
+
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(dates) + pos
+		}
+		if i >= 0 && i < len(dates) {
+			selected = append(selected, dates[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+// matches 判断日期是否满足 BYxxx 条件，未指定日期条件时沿用 dtstart 的星期/日期/月份
+func (r *RRule) matches(dtstart, d time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsMonth(r.ByMonth, d.Month()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 && !matchMonthDay(r.ByMonthDay, d) {
+		return false
+	}
+	if len(r.ByDay) > 0 && !r.matchByDay(d) {
+		return false
+	}
+	if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+		return true
+	}
+
+	switch r.Freq {
+	case "WEEKLY":
+		return d.Weekday() == dtstart.Weekday()
+	case "MONTHLY":
+		return d.Day() == dtstart.Day()
+	case "YEARLY":
+		if len(r.ByMonth) > 0 {
+			return d.Day() == dtstart.Day()
+		}
+		return d.Month() == dtstart.Month() && d.Day() == dtstart.Day()
+	}
+	return true
+}
+
+// matchByDay 判断日期是否满足 BYDAY，序号在 MONTHLY 或指定 BYMONTH 的 YEARLY 中按月计算，否则按年计算
+func (r *RRule) matchByDay(d time.Time) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day != d.Weekday() {
+			continue
+		}
+		if wd.N == 0 || r.Freq == "DAILY" || r.Freq == "WEEKLY" {
+			return true
+		}
+
+		var index, total int
+		if r.Freq == "MONTHLY" || len(r.ByMonth) > 0 {
+			index = (d.Day()-1)/7 + 1
+			total = (daysInMonth(d.Year(), d.Month())-d.Day())/7 + index
+		} else {
+			index = (d.YearDay()-1)/7 + 1
+			total = (daysInYear(d.Year())-d.YearDay())/7 + index
+		}
+		if wd.N == index || wd.N == index-total-1 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMonthDay 判断日期是否满足 BYMONTHDAY，负数表示倒数第几天
+func matchMonthDay(days []int, d time.Time) bool {
+	last := daysInMonth(d.Year(), d.Month())
+	for _, day := range days {
+		if day == d.Day() || (day < 0 && last+day+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMonth(months []time.Month, m time.Month) bool {
+	for _, v := range months {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
+
+// daysInYear 返回指定年份的天数
+func daysInYear(year int) int {
+	return time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNextCycleDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      string
+		cycleValue int
+		cycleUnit  CycleUnit
+		cycleRule  string
+		base       string
+		want       string
+		wantOK     bool
+	}{
+		{"每周", "2026-03-02", 1, CycleUnitWeek, "", "2026-03-02", "2026-03-09", true},
+		{"每两周", "2026-03-02", 2, CycleUnitWeek, "", "2026-03-02", "2026-03-16", true},
+		{"工作日跳过周末", "2026-03-05", 2, CycleUnitBusinessDay, "", "2026-03-05", "2026-03-09", true},
+		{"周五加 1 个工作日", "2026-03-06", 1, CycleUnitBusinessDay, "", "2026-03-06", "2026-03-09", true},
+		{"每月 15 日与月末", "2026-02-01", 1, CycleUnitMonthDays, "15,last", "2026-02-01", "2026-02-15", true},
+		{"15 日之后为月末", "2026-02-01", 1, CycleUnitMonthDays, "15,last", "2026-02-15", "2026-02-28", true},
+		{"月末之后为次月 15 日", "2026-02-01", 1, CycleUnitMonthDays, "15,last", "2026-02-28", "2026-03-15", true},
+		{"每周期跨越两个日期", "2026-02-01", 2, CycleUnitMonthDays, "15,last", "2026-02-15", "2026-03-15", true},
+		{"31 日在小月取月末", "2026-04-01", 1, CycleUnitMonthDays, "31", "2026-04-01", "2026-04-30", true},
+		{"RRULE 每月最后一个周五", "2026-01-30", 1, CycleUnitRRule, "FREQ=MONTHLY;BYDAY=-1FR", "2026-01-30", "2026-02-27", true},
+		{"RRULE 每两周周二周四", "2026-03-03", 1, CycleUnitRRule, "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", "2026-03-05", "2026-03-17", true},
+		{"RRULE 每月最后一个工作日", "2026-01-01", 1, CycleUnitRRule, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2026-01-30", "2026-02-27", true},
+		{"RRULE 每年 2 月 29 日", "2024-02-29", 1, CycleUnitRRule, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "2024-02-29", "2028-02-29", true},
+		{"RRULE 每月 31 日跳过小月", "2026-01-31", 1, CycleUnitRRule, "FREQ=MONTHLY", "2026-01-31", "2026-03-31", true},
+		{"RRULE 远离开始日期", "2020-01-15", 1, CycleUnitRRule, "FREQ=MONTHLY;BYMONTHDAY=15", "2026-03-15", "2026-04-15", true},
+		{"RRULE 次数用尽", "2026-01-01", 1, CycleUnitRRule, "FREQ=MONTHLY;COUNT=3", "2026-03-01", "", false},
+		{"RRULE 截止日期之后", "2026-01-01", 1, CycleUnitRRule, "FREQ=MONTHLY;UNTIL=20260315", "2026-03-01", "", false},
+		{"RRULE 截止日期之前", "2026-01-01", 1, CycleUnitRRule, "FREQ=MONTHLY;UNTIL=20260315", "2026-02-01", "2026-03-01", true},
+		{"RRULE 开始日期在 2 月不存在", "2026-01-30", 1, CycleUnitRRule, "FREQ=YEARLY;BYMONTH=2", "2026-01-30", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{
+				StartDate:  date(tt.start),
+				CycleValue: tt.cycleValue,
+				CycleUnit:  tt.cycleUnit,
+				CycleRule:  tt.cycleRule,
+			}
+			got, ok := sub.NextCycleDate(date(tt.base))
+			if ok != tt.wantOK {
+				t.Fatalf("NextCycleDate(%s) ok = %v, want %v", tt.base, ok, tt.wantOK)
+			}
+			if ok && !got.Equal(date(tt.want)) {
+				t.Errorf("NextCycleDate(%s) = %s, want %s", tt.base, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCycle(t *testing.T) {
+	tests := []struct {
+		unit    CycleUnit
+		rule    string
+		wantErr bool
+	}{
+		{CycleUnitMonth, "", false},
+		{CycleUnitMonthDays, "1,15,last", false},
+		{CycleUnitMonthDays, "", true},
+		{CycleUnitMonthDays, "32", true},
+		{CycleUnitRRule, "FREQ=WEEKLY;BYDAY=MO", false},
+		{CycleUnitRRule, "BYDAY=MO", true},
+		{CycleUnitRRule, "FREQ=HOURLY", true},
+		{CycleUnitRRule, "FREQ=MONTHLY;BYDAY=XX", true},
+		{CycleUnitRRule, "FREQ=MONTHLY;COUNT=2;UNTIL=20270101", true},
+		{CycleUnitRRule, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", false},
+		{CycleUnitRRule, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", true},
+		{CycleUnitRRule, "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=6", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateCycle(tt.unit, tt.rule); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateCycle(%s, %q) error = %v, wantErr %v", tt.unit, tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRuleCycleMonths(t *testing.T) {
+	tests := []struct {
+		name string
+		sub  Subscription
+		want float64
+	}{
+		{"每月两次", Subscription{StartDate: date("2026-01-01"), CycleValue: 1, CycleUnit: CycleUnitMonthDays, CycleRule: "15,last"}, 0.5},
+		{"每季度", Subscription{StartDate: date("2026-01-01"), CycleUnit: CycleUnitRRule, CycleRule: "FREQ=MONTHLY;INTERVAL=3"}, 3},
+		{"每周", Subscription{StartDate: date("2026-01-01"), CycleValue: 1, CycleUnit: CycleUnitWeek}, 7 * 12 / 365.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sub.CycleMonths(); got < tt.want-0.01 || got > tt.want+0.01 {
+				t.Errorf("CycleMonths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
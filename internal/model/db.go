@@ -4,9 +4,13 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
@@ -16,7 +20,13 @@ import (
 	"subdock/internal/config"
 )
 
-var db *gorm.DB
+var (
+	dbPtr atomic.Pointer[gorm.DB] // 当前连接池，恢复快照时原子替换
+	dbMu  sync.Mutex              // 替换数据库文件时串行化
+)
+
+// oldPoolGrace 替换数据库后旧连接池延迟关闭的时间，留给仍持有旧连接池的请求和定时任务完成
+const oldPoolGrace = 30 * time.Second
 
 // InitDB 初始化数据库连接和表结构
 func InitDB() (*gorm.DB, error) {
@@ -27,15 +37,12 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("创建数据目录失败: %w", err)
 	}
 
-	dbPath := filepath.Join(cfg.DataDir, "subdock.db")
+	dbPath := DBPath()
 	firstRun := isFirstRun(dbPath)
 
-	var err error
-	db, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Warn),
-	})
+	conn, err := openDB(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("连接数据库失败: %w", err)
+		return nil, err
 	}
 
 	if firstRun {
@@ -45,16 +52,120 @@ func InitDB() (*gorm.DB, error) {
 	}
 
 	// 启动时执行结构校验与迁移：缺表创建、缺字段补齐、结构按模型同步
-	if err := ensureSchema(db); err != nil {
+	if err := ensureSchema(conn); err != nil {
 		return nil, fmt.Errorf("同步数据库结构失败: %w", err)
 	}
 
 	// 初始化管理员账号
-	if err := initAdmin(); err != nil {
+	if err := initAdmin(conn); err != nil {
 		return nil, fmt.Errorf("初始化管理员失败: %w", err)
 	}
 
-	return db, nil
+	dbPtr.Store(conn)
+	InvalidateSettings()
+	return conn, nil
+}
+
+// DBPath 返回数据库文件路径
+func DBPath() string {
+	return filepath.Join(config.Get().DataDir, "subdock.db")
+}
+
+// openDB 打开数据库连接
+func openDB(dbPath string) (*gorm.DB, error) {
+	conn, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	return conn, nil
+}
+
+// ReplaceDB 用 src 数据库文件替换当前数据库并重新打开连接
+// 新文件先在临时路径完成校验、结构同步与管理员初始化，旧版本快照恢复后可直接使用；
+// 替换文件时持有旧库的排他锁，确保没有进行中的写事务，之后原子切换连接池，旧连接池延迟关闭。
+// 任一步骤失败时继续使用旧连接池
+func ReplaceDB(src string) error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+
+	dbPath := DBPath()
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(src, tmpPath); err != nil {
+		return fmt.Errorf("复制数据库文件失败: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	if err := prepareDB(tmpPath); err != nil {
+		return err
+	}
+
+	old := GetDB()
+	err := old.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("BEGIN EXCLUSIVE").Error; err != nil {
+			return fmt.Errorf("锁定当前数据库失败: %w", err)
+		}
+		defer conn.Exec("ROLLBACK")
+		return os.Rename(tmpPath, dbPath)
+	})
+	if err != nil {
+		return fmt.Errorf("替换数据库文件失败: %w", err)
+	}
+
+	conn, err := openDB(dbPath)
+	if err != nil {
+		return err
+	}
+	dbPtr.Store(conn)
+	InvalidateSettings()
+	time.AfterFunc(oldPoolGrace, func() {
+		if sqlDB, err := old.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return nil
+}
+
+// prepareDB 校验待恢复的数据库文件并同步结构、初始化管理员
+func prepareDB(path string) error {
+	conn, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	var result string
+	if err := conn.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil || result != "ok" {
+		return fmt.Errorf("数据库文件校验失败: %v %s", err, result)
+	}
+	if err := ensureSchema(conn); err != nil {
+		return fmt.Errorf("同步数据库结构失败: %w", err)
+	}
+	return initAdmin(conn)
+}
+
+// copyFile 复制文件
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
 // isFirstRun 判断是否首次运行（数据库文件不存在）
@@ -65,7 +176,7 @@ func isFirstRun(dbPath string) bool {
 
 // ensureSchema 确保数据库结构与模型一致
 func ensureSchema(db *gorm.DB) error {
-	models := []interface{}{&Admin{}, &Subscription{}, &SubscriptionRenewal{}, &Setting{}}
+	models := []interface{}{&Admin{}, &Category{}, &Tag{}, &Subscription{}, &SubscriptionRenewal{}, &Setting{}, &ExchangeRate{}, &Budget{}, &BudgetAlert{}, &JobRun{}}
 	migrator := db.Migrator()
 
 	// 1) 缺表时创建
@@ -90,20 +201,54 @@ func ensureSchema(db *gorm.DB) error {
 	}
 
 	// 3) 按模型执行自动迁移（类型/索引等结构同步）
+	hadRenewalAmount := migrator.HasColumn(&SubscriptionRenewal{}, "amount")
+	hadRemindOffsets := migrator.HasColumn(&Subscription{}, "remind_offsets")
+	hadAnchorDay := migrator.HasColumn(&Subscription{}, "anchor_day")
 	if err := db.AutoMigrate(models...); err != nil {
 		return fmt.Errorf("自动迁移失败: %w", err)
 	}
 
+	// 4) 历史续订记录补齐金额与币种（按订阅当前金额回填）
+	if !hadRenewalAmount {
+		if err := db.Exec(`UPDATE subscription_renewals SET
+			amount = COALESCE((SELECT amount FROM subscriptions WHERE subscriptions.id = subscription_renewals.subscription_id), 0),
+			currency = COALESCE((SELECT currency FROM subscriptions WHERE subscriptions.id = subscription_renewals.subscription_id), 'CNY')`).Error; err != nil {
+			return fmt.Errorf("回填续订记录金额失败: %w", err)
+		}
+	}
+
+	// 5) 旧版单一提醒天数迁移为提醒偏移：提前 N 天、前 1 天和当天，迁移后删除旧字段
+	if migrator.HasColumn(&Subscription{}, "remind_days") {
+		if !hadRemindOffsets {
+			if err := db.Exec(`UPDATE subscriptions SET remind_offsets = CASE
+				WHEN remind_days <= 0 THEN '0'
+				WHEN remind_days = 1 THEN '1,0'
+				ELSE remind_days || ',1,0' END`).Error; err != nil {
+				return fmt.Errorf("迁移提醒天数失败: %w", err)
+			}
+		}
+		if err := migrator.DropColumn(&Subscription{}, "remind_days"); err != nil {
+			return fmt.Errorf("删除 subscriptions.remind_days 失败: %w", err)
+		}
+	}
+
+	// 6) 补齐账单日：取当前到期日期的日，已有订阅的到期日期与续订日保持不变
+	if !hadAnchorDay {
+		if err := db.Exec(`UPDATE subscriptions SET anchor_day = CAST(strftime('%d', expire_date) AS INTEGER) WHERE anchor_day = 0`).Error; err != nil {
+			return fmt.Errorf("补齐账单日失败: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
-	return db
+	return dbPtr.Load()
 }
 
 // initAdmin 如果不存在管理员账号，则创建一个
-func initAdmin() error {
+func initAdmin(db *gorm.DB) error {
 	var count int64
 	if err := db.Model(&Admin{}).Count(&count).Error; err != nil {
 		return err
@@ -8,12 +8,14 @@ import (
 
 // Admin 管理员账号
 type Admin struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	Username     string         `gorm:"uniqueIndex;size:64;not null" json:"username"`
-	PasswordHash string         `gorm:"size:256;not null" json:"-"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint           `gorm:"primarykey" json:"id"`
+	Username      string         `gorm:"uniqueIndex;size:64;not null" json:"username"`
+	PasswordHash  string         `gorm:"size:256;not null" json:"-"`
+	CalendarToken string         `gorm:"size:64;index" json:"-"`  // 日历订阅令牌，为空表示尚未生成
+	Timezone      string         `gorm:"size:64" json:"timezone"` // 统计、日历等视图使用的时区，为空使用全局时区
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // CycleUnit 周期单位
@@ -25,25 +27,67 @@ const (
 	CycleUnitQuarter  CycleUnit = "quarter"
 	CycleUnitHalfYear CycleUnit = "half_year"
 	CycleUnitYear     CycleUnit = "year"
+
+	CycleUnitWeek        CycleUnit = "week"         // 每 N 周
+	CycleUnitBusinessDay CycleUnit = "business_day" // 每 N 个工作日（周一至周五）
+	CycleUnitMonthDays   CycleUnit = "month_days"   // 每月固定日期，CycleRule 如 "15,last"，CycleValue 为每周期跨越的日期数
+	CycleUnitRRule       CycleUnit = "rrule"        // RFC 5545 重复规则，CycleRule 如 "FREQ=MONTHLY;BYDAY=-1FR"，以开始日期为起点
+)
+
+// SubscriptionStatus 订阅状态
+type SubscriptionStatus string
+
+const (
+	StatusActive    SubscriptionStatus = "active"
+	StatusPaused    SubscriptionStatus = "paused"
+	StatusCancelled SubscriptionStatus = "cancelled"
 )
 
 // Subscription 订阅
 type Subscription struct {
-	ID         uint           `gorm:"primarykey" json:"id"`
-	Name       string         `gorm:"size:128;not null" json:"name"`
-	Amount     float64        `gorm:"not null" json:"amount"`
-	Currency   string         `gorm:"size:8;default:CNY" json:"currency"`
-	StartDate  time.Time      `gorm:"not null" json:"start_date"`
-	CycleValue int            `gorm:"not null;default:1" json:"cycle_value"`
-	CycleUnit  CycleUnit      `gorm:"size:16;not null;default:month" json:"cycle_unit"`
-	ExpireDate time.Time      `gorm:"not null" json:"expire_date"`
-	AutoRenew  bool           `gorm:"not null;default:false" json:"auto_renew"`
-	RenewCount int            `gorm:"not null;default:0" json:"renew_count"`
-	RemindDays int            `gorm:"not null;default:3" json:"remind_days"`
-	Remark     string         `gorm:"size:512" json:"remark"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint               `gorm:"primarykey" json:"id"`
+	Name           string             `gorm:"size:128;not null" json:"name"`
+	Amount         float64            `gorm:"not null" json:"amount"`
+	Currency       string             `gorm:"size:8;default:CNY" json:"currency"`
+	StartDate      time.Time          `gorm:"not null" json:"start_date"`
+	BillingType    BillingType        `gorm:"size:16;not null;default:recurring" json:"billing_type"`
+	CycleValue     int                `gorm:"not null;default:1" json:"cycle_value"`
+	CycleUnit      CycleUnit          `gorm:"size:16;not null;default:month" json:"cycle_unit"`
+	CycleRule      string             `gorm:"size:256" json:"cycle_rule"`        // month_days 与 rrule 周期的规则
+	ExpireDate     time.Time          `gorm:"not null;index" json:"expire_date"` // 不会到期时为 NoExpireDate
+	SupportEndDate *time.Time         `json:"support_end_date"`                  // 支持/更新截止日期，到期前同样按提醒偏移提醒
+	AutoRenew      bool               `gorm:"not null;default:false" json:"auto_renew"`
+	RenewCount     int                `gorm:"not null;default:0" json:"renew_count"`
+	AnchorDay      int                `gorm:"not null;default:0" json:"anchor_day"` // 账单日（1-31），按月计算的周期以此为准；0 表示取开始日期当天
+	RemindOffsets  string             `gorm:"size:128" json:"remind_offsets"`       // 提醒偏移天数，逗号分隔：正数为到期前，0 为当天，负数为到期后；为空使用全局默认
+	Status         SubscriptionStatus `gorm:"size:16;not null;default:active;index" json:"status"`
+	SnoozeUntil    *time.Time         `gorm:"index" json:"snooze_until"` // 暂停提醒截止日期（不含），为空表示未暂停
+	AckedAt        *time.Time         `json:"acked_at"`                  // 确认已知晓本周期提醒的时间
+	AckedExpire    *time.Time         `json:"acked_expire"`              // 确认的提醒日期（到期或支持/更新截止日期），日期变化后确认自动失效
+	Timezone       string             `gorm:"size:64" json:"timezone"`   // 提醒与到期判断使用的时区，为空使用全局时区
+	CategoryID     *uint              `gorm:"index" json:"category_id"`
+	Category       *Category          `json:"category,omitempty"`
+	Tags           []Tag              `gorm:"many2many:subscription_tags;" json:"tags"`
+	Remark         string             `gorm:"size:512" json:"remark"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt     `gorm:"index" json:"-"`
+}
+
+// Category 订阅分类，每个订阅最多属于一个分类
+type Category struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"uniqueIndex;size:64;not null" json:"name"`
+	Color     string    `gorm:"size:16" json:"color"`
+	Icon      string    `gorm:"size:64" json:"icon"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Tag 订阅标签，与订阅多对多关联
+type Tag struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Name string `gorm:"uniqueIndex;size:64;not null" json:"name"`
 }
 
 // SubscriptionRenewal 订阅续订记录
@@ -54,6 +98,9 @@ type SubscriptionRenewal struct {
 	OldExpireDate  time.Time `gorm:"not null" json:"old_expire_date"`
 	NewExpireDate  time.Time `gorm:"not null" json:"new_expire_date"`
 	RenewCount     int       `gorm:"not null" json:"renew_count"`
+	Amount         float64   `gorm:"not null;default:0" json:"amount"`
+	Currency       string    `gorm:"size:8;default:CNY" json:"currency"`
+	Auto           bool      `gorm:"not null;default:false" json:"auto"` // 是否由自动续订产生
 }
 
 // CalculateExpireDate 根据开始日期、周期和续订次数计算到期日期
@@ -67,30 +114,151 @@ func (s *Subscription) CalculateExpireDate() time.Time {
 	return base
 }
 
-// CalculateExpireDateFrom 根据给定基准日期和周期计算到期日期
+// CalculateExpireDateFrom 根据给定基准日期和周期计算到期日期，规则周期已用尽时返回 base
+// 按月计算的周期以账单日为准，超出当月天数时取月末，之后的周期恢复到账单日（1/31 → 2/28 → 3/31）
 func (s *Subscription) CalculateExpireDateFrom(base time.Time) time.Time {
+	next, _ := s.NextCycleDate(base)
+	return next
+}
+
+// BillingDay 从 base 起算时使用的账单日
+// base 落在账单日，或因当月天数不足落在月末时沿用 AnchorDay，否则以 base 当天为账单日
+func (s *Subscription) BillingDay(base time.Time) int {
+	anchor := s.AnchorDay
+	if anchor <= 0 || anchor > 31 {
+		anchor = s.StartDate.Day()
+	}
+	if base.Day() == anchor || (base.Day() < anchor && base.Day() == daysInMonth(base.Year(), base.Month())) {
+		return anchor
+	}
+	return base.Day()
+}
+
+// addMonths 在 base 上增加 months 个月，日期取 day，超出当月天数时取月末
+func addMonths(base time.Time, months, day int) time.Time {
+	y, m := base.Year(), base.Month()+time.Month(months)
+	first := time.Date(y, m, 1, base.Hour(), base.Minute(), base.Second(), base.Nanosecond(), base.Location())
+	if last := daysInMonth(first.Year(), first.Month()); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// daysInMonth 返回指定月份的天数
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// CycleMonths 单个计费周期折合的月数
+func (s *Subscription) CycleMonths() float64 {
+	value := float64(s.CycleValue)
+	if value <= 0 {
+		value = 1
+	}
 	switch s.CycleUnit {
 	case CycleUnitDay:
-		return base.AddDate(0, 0, s.CycleValue)
-	case CycleUnitMonth:
-		return base.AddDate(0, s.CycleValue, 0)
+		return value * 12 / 365.25
+	case CycleUnitWeek:
+		return value * 7 * 12 / 365.25
+	case CycleUnitBusinessDay:
+		return value * 12 / 261
 	case CycleUnitQuarter:
-		return base.AddDate(0, s.CycleValue*3, 0)
+		return value * 3
 	case CycleUnitHalfYear:
-		return base.AddDate(0, s.CycleValue*6, 0)
+		return value * 6
 	case CycleUnitYear:
-		return base.AddDate(s.CycleValue, 0, 0)
+		return value * 12
+	case CycleUnitMonthDays, CycleUnitRRule:
+		// 按开始日期后一年内的实际次数折算，一年内不足一次时按一年一次计算
+		perYear := s.cyclesPerYear()
+		if perYear == 0 {
+			return 12
+		}
+		if s.CycleUnit == CycleUnitRRule {
+			value = 1
+		}
+		return value * 12 / float64(perYear)
 	default:
-		return base.AddDate(0, s.CycleValue, 0)
+		return value
 	}
 }
 
-// ShouldRemindToday 判断今天是否应该提醒
+// MonthlyCost 折合每月费用，一次性购买与永久授权不计入周期费用
+func (s *Subscription) MonthlyCost() float64 {
+	if !s.IsRecurring() {
+		return 0
+	}
+	return s.Amount / s.CycleMonths()
+}
+
+// YearlyCost 折合每年费用
+func (s *Subscription) YearlyCost() float64 {
+	return s.MonthlyCost() * 12
+}
+
+// ShouldRemindToday 判断订阅时区下的今天是否命中提醒偏移，已暂停或已确认该日期的提醒时不再提醒
 func (s *Subscription) ShouldRemindToday() bool {
-	today := time.Now().Truncate(24 * time.Hour)
-	remindDate := s.ExpireDate.AddDate(0, 0, -s.RemindDays).Truncate(24 * time.Hour)
-	expireDate := s.ExpireDate.Truncate(24 * time.Hour)
-	return !today.Before(remindDate) && !today.After(expireDate)
+	today := s.Today()
+	if s.IsSnoozed(today) {
+		return false
+	}
+	date, _, ok := s.RemindTargetOn(today)
+	return ok && !s.IsAcknowledged(date)
+}
+
+// IsSnoozed 判断 day 当天提醒是否处于暂停状态
+func (s *Subscription) IsSnoozed(day time.Time) bool {
+	return s.SnoozeUntil != nil && CalendarDate(day).Before(CalendarDate(*s.SnoozeUntil))
+}
+
+// IsAcknowledged 判断 date（到期或支持/更新截止日期）的提醒是否已确认
+func (s *Subscription) IsAcknowledged(date time.Time) bool {
+	return s.AckedExpire != nil && CalendarDate(*s.AckedExpire).Equal(CalendarDate(date))
+}
+
+// ExchangeRate 汇率记录，表示 1 单位 Base 可兑换 Rate 单位 Currency
+// 按日期保留历史汇率，历史支出按支付当日汇率换算
+type ExchangeRate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Base      string    `gorm:"size:8;not null;uniqueIndex:idx_rate_base_currency_date" json:"base"`
+	Currency  string    `gorm:"size:8;not null;uniqueIndex:idx_rate_base_currency_date" json:"currency"`
+	Rate      float64   `gorm:"not null" json:"rate"`
+	RateDate  time.Time `gorm:"not null;uniqueIndex:idx_rate_base_currency_date" json:"rate_date"`
+	Source    string    `gorm:"size:32" json:"source"`
+	FetchedAt time.Time `gorm:"not null" json:"fetched_at"`
+}
+
+// BudgetPeriod 预算周期
+type BudgetPeriod string
+
+const (
+	BudgetPeriodMonth BudgetPeriod = "month"
+	BudgetPeriodYear  BudgetPeriod = "year"
+)
+
+// Budget 预算
+type Budget struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	Name       string       `gorm:"size:64;not null" json:"name"`
+	Period     BudgetPeriod `gorm:"size:16;not null;default:month" json:"period"`
+	CategoryID *uint        `gorm:"index" json:"category_id"` // 为空表示总预算
+	Amount     float64      `gorm:"not null" json:"amount"`
+	Currency   string       `gorm:"size:8;not null;default:CNY" json:"currency"`
+	Thresholds string       `gorm:"size:64;not null;default:'80,100'" json:"thresholds"` // 告警阈值百分比，逗号分隔
+	Enabled    bool         `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// BudgetAlert 预算告警记录，同一预算周期内每个阈值每种类型只告警一次
+type BudgetAlert struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	BudgetID  uint      `gorm:"not null;uniqueIndex:idx_budget_alert" json:"budget_id"`
+	PeriodKey string    `gorm:"size:8;not null;uniqueIndex:idx_budget_alert" json:"period_key"` // 2024 或 2024-01
+	Threshold int       `gorm:"not null;uniqueIndex:idx_budget_alert" json:"threshold"`
+	Kind      string    `gorm:"size:16;not null;uniqueIndex:idx_budget_alert" json:"kind"` // actual 或 projected
+	Spent     float64   `gorm:"not null" json:"spent"`
+	AlertedAt time.Time `gorm:"not null" json:"alerted_at"`
 }
 
 // Setting 系统设置
@@ -99,3 +267,14 @@ type Setting struct {
 	Key   string `gorm:"uniqueIndex;size:64;not null" json:"key"`
 	Value string `gorm:"type:text" json:"value"`
 }
+
+// JobRun 定时任务最近一次执行记录，每个任务一行
+type JobRun struct {
+	ID            uint       `gorm:"primarykey" json:"-"`
+	Name          string     `gorm:"uniqueIndex;size:32;not null" json:"name"`
+	LastRunAt     time.Time  `gorm:"not null" json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"` // 最近一次成功执行的开始时间，从未成功时为空
+	DurationMs    int64      `gorm:"not null;default:0" json:"duration_ms"`
+	Result        string     `gorm:"size:16;not null" json:"result"` // success 或 failed
+	Error         string     `gorm:"type:text" json:"error"`
+}
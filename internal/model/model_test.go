@@ -0,0 +1,94 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCalculateExpireDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		start      string
+		cycleValue int
+		cycleUnit  CycleUnit
+		renewCount int
+		anchorDay  int
+		want       string
+	}{
+		{"按天", "2026-01-01", 30, CycleUnitDay, 0, 0, "2026-01-31"},
+		{"按天跨闰日", "2024-02-28", 1, CycleUnitDay, 0, 0, "2024-02-29"},
+		{"按月", "2026-01-15", 1, CycleUnitMonth, 0, 0, "2026-02-15"},
+		{"按月含续订次数", "2026-01-15", 1, CycleUnitMonth, 2, 0, "2026-04-15"},
+		{"1 月 31 日加 1 个月取月末", "2026-01-31", 1, CycleUnitMonth, 0, 0, "2026-02-28"},
+		{"闰年 1 月 31 日加 1 个月", "2024-01-31", 1, CycleUnitMonth, 0, 0, "2024-02-29"},
+		{"月末之后恢复账单日", "2026-01-31", 1, CycleUnitMonth, 1, 0, "2026-03-31"},
+		{"31 日经过 30 天的月份", "2026-03-31", 1, CycleUnitMonth, 1, 0, "2026-05-31"},
+		{"指定账单日", "2026-01-15", 1, CycleUnitMonth, 1, 31, "2026-03-15"},
+		{"按季度取月末", "2026-11-30", 1, CycleUnitQuarter, 0, 0, "2027-02-28"},
+		{"按季度恢复账单日", "2026-11-30", 1, CycleUnitQuarter, 1, 0, "2027-05-30"},
+		{"半年", "2026-01-15", 1, CycleUnitHalfYear, 0, 0, "2026-07-15"},
+		{"两个半年", "2026-01-15", 2, CycleUnitHalfYear, 0, 0, "2027-01-15"},
+		{"半年落在 2 月取月末", "2025-08-31", 1, CycleUnitHalfYear, 0, 0, "2026-02-28"},
+		{"半年恢复账单日", "2025-08-31", 1, CycleUnitHalfYear, 1, 0, "2026-08-31"},
+		{"按年", "2026-03-01", 1, CycleUnitYear, 0, 0, "2027-03-01"},
+		{"闰日加 1 年", "2024-02-29", 1, CycleUnitYear, 0, 0, "2025-02-28"},
+		{"闰日逐年续订回到闰日", "2024-02-29", 1, CycleUnitYear, 3, 0, "2028-02-29"},
+		{"闰日加 4 年", "2024-02-29", 4, CycleUnitYear, 0, 0, "2028-02-29"},
+		{"未知单位按月", "2026-01-15", 1, CycleUnit("fortnight"), 0, 0, "2026-02-15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{
+				StartDate:  date(tt.start),
+				CycleValue: tt.cycleValue,
+				CycleUnit:  tt.cycleUnit,
+				RenewCount: tt.renewCount,
+				AnchorDay:  tt.anchorDay,
+			}
+			want := date(tt.want)
+			if got := sub.CalculateExpireDate(); !got.Equal(want) {
+				t.Errorf("CalculateExpireDate() = %s, want %s", got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateExpireDateFromOffAnchorBase(t *testing.T) {
+	sub := Subscription{StartDate: date("2026-01-31"), CycleValue: 1, CycleUnit: CycleUnitMonth, AnchorDay: 31}
+
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"2026-01-31", "2026-02-28"},
+		{"2026-02-28", "2026-03-31"},
+		{"2026-04-30", "2026-05-31"},
+		// 过期后从今天续订，以今天为账单日
+		{"2026-03-20", "2026-04-20"},
+	}
+
+	for _, tt := range tests {
+		if got := sub.CalculateExpireDateFrom(date(tt.base)); !got.Equal(date(tt.want)) {
+			t.Errorf("CalculateExpireDateFrom(%s) = %s, want %s", tt.base, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestMigratedAnchorKeepsBillingDay(t *testing.T) {
+	// 旧版 1/31 起按月计算到期日为 3/3，迁移后账单日取到期日的 3 日
+	sub := Subscription{StartDate: date("2026-01-31"), CycleValue: 1, CycleUnit: CycleUnitMonth, AnchorDay: 3}
+
+	next := sub.CalculateExpireDateFrom(date("2026-03-03"))
+	if want := date("2026-04-03"); !next.Equal(want) {
+		t.Errorf("CalculateExpireDateFrom(2026-03-03) = %s, want 2026-04-03", next.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// 过期提醒相关设置项
+const (
+	SettingOverdueInterval      = "overdue_remind_interval" // 过期后每隔 N 天提醒一次，0 表示不提醒
+	SettingOverdueMax           = "overdue_remind_max"      // 过期提醒的最多次数
+	SettingOverdueEscalateAfter = "overdue_escalate_after"  // 过期 N 天后同时发送到升级通知渠道，0 表示不升级
+
+	SettingOverdueEscalateTelegramChatID = "overdue_escalate_telegram_chat_id" // 升级通知发送到的 Telegram 会话
+	SettingOverdueEscalateBarkURL        = "overdue_escalate_bark_url"         // 升级通知发送到的 Bark 地址
+)
+
+// 过期提醒默认值：每 3 天提醒一次，最多 3 次，不升级
+const (
+	DefaultOverdueInterval      = 3
+	DefaultOverdueMax           = 3
+	DefaultOverdueEscalateAfter = 0
+)
+
+// maxOverdueSetting 过期提醒各项设置的上限
+const maxOverdueSetting = 365
+
+// OverduePolicy 过期提醒策略
+type OverduePolicy struct {
+	Interval      int `json:"interval"`       // 提醒间隔天数，0 表示不提醒
+	Max           int `json:"max"`            // 最多提醒次数
+	EscalateAfter int `json:"escalate_after"` // 过期多少天后升级，0 表示不升级
+}
+
+// OverdueReminder 某一天命中的过期提醒
+type OverdueReminder struct {
+	Days     int  // 已过期天数
+	Count    int  // 第几次过期提醒
+	Escalate bool // 是否需要升级通知
+}
+
+// ParseOverdueSetting 解析过期提醒设置值（0-365 的整数）
+func ParseOverdueSetting(s string) (int, bool) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 || v > maxOverdueSetting {
+		return 0, false
+	}
+	return v, true
+}
+
+// GlobalOverduePolicy 读取全局过期提醒策略，未配置或配置无效的项使用默认值
+func GlobalOverduePolicy() OverduePolicy {
+	return OverduePolicy{
+		Interval:      overdueSetting(SettingOverdueInterval, DefaultOverdueInterval),
+		Max:           overdueSetting(SettingOverdueMax, DefaultOverdueMax),
+		EscalateAfter: overdueSetting(SettingOverdueEscalateAfter, DefaultOverdueEscalateAfter),
+	}
+}
+
+// overdueSetting 读取单项过期提醒设置，未配置或配置无效时返回 def
+func overdueSetting(key string, def int) int {
+	if value, ok := LookupSetting(key); ok {
+		if v, ok := ParseOverdueSetting(value); ok {
+			return v
+		}
+	}
+	return def
+}
+
+// OverdueDays 返回 day 当天订阅已过期的天数；未过期、不会到期或会自动续订的订阅返回 false
+func (s *Subscription) OverdueDays(day time.Time) (int, bool) {
+	if !s.HasExpiry() || (s.AutoRenew && s.IsRecurring()) {
+		return 0, false
+	}
+	expire := CalendarDate(s.ExpireDate)
+	day = CalendarDate(day)
+	if !day.After(expire) {
+		return 0, false
+	}
+	return int(day.Sub(expire).Hours() / 24), true
+}
+
+// OverdueReminderOn 判断 day 当天是否命中过期提醒：过期第 Interval、2×Interval… 天提醒，最多 Max 次
+// 已暂停、已确认到期或当天已有常规提醒（如负数提醒偏移）时不重复提醒
+func (s *Subscription) OverdueReminderOn(day time.Time, policy OverduePolicy) (OverdueReminder, bool) {
+	days, ok := s.OverdueDays(day)
+	if !ok || policy.Interval <= 0 || days%policy.Interval != 0 {
+		return OverdueReminder{}, false
+	}
+	count := days / policy.Interval
+	if count > policy.Max || s.IsSnoozed(day) || s.IsAcknowledged(s.ExpireDate) {
+		return OverdueReminder{}, false
+	}
+	if _, _, ok := s.RemindTargetOn(day); ok {
+		return OverdueReminder{}, false
+	}
+	return OverdueReminder{
+		Days:     days,
+		Count:    count,
+		Escalate: policy.EscalateAfter > 0 && days >= policy.EscalateAfter,
+	}, true
+}
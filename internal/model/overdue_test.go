@@ -0,0 +1,56 @@
+package model
+
+import "testing"
+
+func TestOverdueReminderOn(t *testing.T) {
+	policy := OverduePolicy{Interval: 3, Max: 3, EscalateAfter: 6}
+	sub := Subscription{ExpireDate: date("2026-03-10"), RemindOffsets: "7,1,0,-3"}
+
+	tests := []struct {
+		day          string
+		want         bool
+		wantCount    int
+		wantEscalate bool
+	}{
+		{"2026-03-10", false, 0, false}, // 到期当天
+		{"2026-03-12", false, 0, false}, // 不在提醒间隔上
+		{"2026-03-13", false, 0, false}, // 已由 -3 的提醒偏移提醒
+		{"2026-03-16", true, 2, true},
+		{"2026-03-19", true, 3, true},
+		{"2026-03-22", false, 0, false}, // 超过最多次数
+	}
+	for _, tt := range tests {
+		got, ok := sub.OverdueReminderOn(date(tt.day), policy)
+		if ok != tt.want || got.Count != tt.wantCount || got.Escalate != tt.wantEscalate {
+			t.Errorf("OverdueReminderOn(%s) = %+v, %v, want count %d escalate %v, %v",
+				tt.day, got, ok, tt.wantCount, tt.wantEscalate, tt.want)
+		}
+	}
+}
+
+func TestOverdueReminderSkipped(t *testing.T) {
+	policy := OverduePolicy{Interval: 1, Max: 10}
+	day := date("2026-03-12")
+	expire := date("2026-03-10")
+	snooze := date("2026-03-15")
+
+	tests := map[string]Subscription{
+		"自动续订":  {ExpireDate: expire, AutoRenew: true},
+		"永久授权":  {BillingType: BillingLifetime, ExpireDate: NoExpireDate},
+		"已确认到期": {ExpireDate: expire, AckedExpire: &expire},
+		"已暂停":   {ExpireDate: expire, SnoozeUntil: &snooze},
+	}
+	for name, sub := range tests {
+		if _, ok := sub.OverdueReminderOn(day, policy); ok {
+			t.Errorf("%s: 不应发送过期提醒", name)
+		}
+	}
+
+	sub := Subscription{ExpireDate: expire, RemindOffsets: "0"}
+	if _, ok := sub.OverdueReminderOn(day, OverduePolicy{}); ok {
+		t.Error("间隔为 0 时不应发送过期提醒")
+	}
+	if got, ok := sub.OverdueReminderOn(day, policy); !ok || got.Days != 2 || got.Escalate {
+		t.Errorf("OverdueReminderOn() = %+v, %v", got, ok)
+	}
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRemindOffsets 未配置全局默认时使用的提醒偏移
+const DefaultRemindOffsets = "7,1,0"
+
+// SettingRemindOffsets 全局默认提醒偏移的设置项
+const SettingRemindOffsets = "remind_offsets"
+
+// maxRemindOffset 提醒偏移天数的绝对值上限
+const maxRemindOffset = 365
+
+// ParseRemindOffsets 解析逗号分隔的提醒偏移天数，返回降序去重后的结果
+// 正数表示到期前 N 天，0 表示到期当天，负数表示到期后 N 天
+func ParseRemindOffsets(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	var offsets []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil || v < -maxRemindOffset || v > maxRemindOffset {
+			return nil, fmt.Errorf("无效的提醒偏移: %s", p)
+		}
+		if !seen[v] {
+			seen[v] = true
+			offsets = append(offsets, v)
+		}
+	}
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("至少需要一个提醒偏移")
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+	return offsets, nil
+}
+
+// FormatRemindOffsets 将提醒偏移格式化为逗号分隔的字符串
+func FormatRemindOffsets(offsets []int) string {
+	parts := make([]string, len(offsets))
+	for i, v := range offsets {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
+
+// NormalizeRemindOffsets 校验并规范化提醒偏移字符串，空字符串表示使用全局默认
+func NormalizeRemindOffsets(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	offsets, err := ParseRemindOffsets(s)
+	if err != nil {
+		return "", err
+	}
+	return FormatRemindOffsets(offsets), nil
+}
+
+// LegacyRemindOffsets 将旧版“提前 N 天提醒”换算为提醒偏移：提前 N 天、前 1 天和当天
+func LegacyRemindOffsets(days int) string {
+	if days <= 0 {
+		return "0"
+	}
+	offsets, _ := ParseRemindOffsets(fmt.Sprintf("%d,1,0", days))
+	return FormatRemindOffsets(offsets)
+}
+
+// GlobalRemindOffsets 读取全局默认提醒偏移，未配置或配置无效时使用 DefaultRemindOffsets
+func GlobalRemindOffsets() []int {
+	if value, ok := LookupSetting(SettingRemindOffsets); ok {
+		if offsets, err := ParseRemindOffsets(value); err == nil {
+			return offsets
+		}
+	}
+	offsets, _ := ParseRemindOffsets(DefaultRemindOffsets)
+	return offsets
+}
+
+// EffectiveRemindOffsets 订阅实际使用的提醒偏移，未单独设置时使用全局默认
+func (s *Subscription) EffectiveRemindOffsets() []int {
+	if offsets, err := ParseRemindOffsets(s.RemindOffsets); err == nil {
+		return offsets
+	}
+	return GlobalRemindOffsets()
+}
+
+// RemindOffsetOn 返回 day 当天命中的提醒偏移，day 按其所属时区取日历日期
+func (s *Subscription) RemindOffsetOn(day time.Time) (int, bool) {
+	_, offset, ok := s.RemindTargetOn(day)
+	return offset, ok
+}
+
+// RemindTargetOn 返回 day 当天命中提醒的日期（到期或支持/更新截止日期）及提醒偏移
+func (s *Subscription) RemindTargetOn(day time.Time) (time.Time, int, bool) {
+	day = CalendarDate(day)
+	offsets := s.EffectiveRemindOffsets()
+	for _, date := range s.ReminderDates() {
+		for _, offset := range offsets {
+			if date.AddDate(0, 0, -offset).Equal(day) {
+				return date, offset, true
+			}
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// InReminderWindow 判断 day 是否处于某个提醒日期的最早一次提醒与该日期之间，用于统计“即将到期”
+func (s *Subscription) InReminderWindow(day time.Time) bool {
+	day = CalendarDate(day)
+	lead := s.EffectiveRemindOffsets()[0]
+	if lead < 0 {
+		lead = 0
+	}
+	for _, date := range s.ReminderDates() {
+		if !day.Before(date.AddDate(0, 0, -lead)) && !day.After(date) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+)
+
+func TestParseRemindOffsets(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"7,1,0", []int{7, 1, 0}, false},
+		{" 0, 30 ,7,-3,7 ", []int{30, 7, 0, -3}, false},
+		{"-1", []int{-1}, false},
+		{"", nil, true},
+		{" , ", nil, true},
+		{"a", nil, true},
+		{"366", nil, true},
+		{"-366", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRemindOffsets(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRemindOffsets(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRemindOffsets(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLegacyRemindOffsets(t *testing.T) {
+	tests := map[int]string{-1: "0", 0: "0", 1: "1,0", 3: "3,1,0", 30: "30,1,0"}
+	for days, want := range tests {
+		if got := LegacyRemindOffsets(days); got != want {
+			t.Errorf("LegacyRemindOffsets(%d) = %q, want %q", days, got, want)
+		}
+	}
+}
+
+func TestRemindOffsetOn(t *testing.T) {
+	sub := Subscription{ExpireDate: date("2026-03-10"), RemindOffsets: "7,1,0,-3"}
+
+	tests := []struct {
+		day        string
+		wantOffset int
+		wantOK     bool
+	}{
+		{"2026-03-02", 0, false},
+		{"2026-03-03", 7, true},
+		{"2026-03-05", 0, false},
+		{"2026-03-09", 1, true},
+		{"2026-03-10", 0, true},
+		{"2026-03-11", 0, false},
+		{"2026-03-13", -3, true},
+	}
+
+	for _, tt := range tests {
+		offset, ok := sub.RemindOffsetOn(date(tt.day))
+		if offset != tt.wantOffset || ok != tt.wantOK {
+			t.Errorf("RemindOffsetOn(%s) = (%d, %v), want (%d, %v)", tt.day, offset, ok, tt.wantOffset, tt.wantOK)
+		}
+	}
+}
+
+func TestRemindOffsetOnUsesLocalCalendarDate(t *testing.T) {
+	sub := Subscription{ExpireDate: date("2026-03-10"), RemindOffsets: "0"}
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+
+	// UTC 3 月 9 日 17:00 在上海已是 3 月 10 日
+	day := time.Date(2026, 3, 9, 17, 0, 0, 0, time.UTC)
+	if _, ok := sub.RemindOffsetOn(day); ok {
+		t.Error("RemindOffsetOn(UTC 03-09) should not match")
+	}
+	if _, ok := sub.RemindOffsetOn(day.In(shanghai)); !ok {
+		t.Error("RemindOffsetOn(Shanghai 03-10) should match")
+	}
+}
+
+func TestInReminderWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		offsets string
+		day     string
+		want    bool
+	}{
+		{"窗口之前", "7,1,0", "2026-03-02", false},
+		{"最早提醒当天", "7,1,0", "2026-03-03", true},
+		{"两次提醒之间", "7,1,0", "2026-03-06", true},
+		{"到期当天", "7,1,0", "2026-03-10", true},
+		{"到期之后", "7,1,0", "2026-03-11", false},
+		{"只在到期后提醒", "-1,-3", "2026-03-10", true},
+		{"只在到期后提醒的前一天", "-1,-3", "2026-03-09", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := Subscription{ExpireDate: date("2026-03-10"), RemindOffsets: tt.offsets}
+			if got := sub.InReminderWindow(date(tt.day)); got != tt.want {
+				t.Errorf("InReminderWindow(%s) = %v, want %v", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRemindToday(t *testing.T) {
+	expire := date("2026-03-10")
+	snoozeUntil := date("2026-03-11")
+	snoozeEnded := date("2026-03-10")
+
+	tests := []struct {
+		name string
+		now  time.Time
+		sub  Subscription
+		want bool
+	}{
+		{
+			name: "到期当天",
+			now:  time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "UTC"},
+			want: true,
+		},
+		{
+			name: "非提醒日",
+			now:  time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "UTC"},
+			want: false,
+		},
+		{
+			name: "订阅时区已进入到期日",
+			now:  time.Date(2026, 3, 9, 17, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "Asia/Shanghai"},
+			want: true,
+		},
+		{
+			name: "订阅时区尚未进入到期日",
+			now:  time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "America/New_York"},
+			want: false,
+		},
+		{
+			name: "暂停中",
+			now:  time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "UTC", SnoozeUntil: &snoozeUntil},
+			want: false,
+		},
+		{
+			name: "暂停已结束",
+			now:  time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "UTC", SnoozeUntil: &snoozeEnded},
+			want: true,
+		},
+		{
+			name: "已确认本周期",
+			now:  time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+			sub:  Subscription{Timezone: "UTC", AckedExpire: &expire},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(clock.Set(clock.NewFixed(tt.now)))
+
+			sub := tt.sub
+			sub.ExpireDate = expire
+			sub.RemindOffsets = "0"
+			if got := sub.ShouldRemindToday(); got != tt.want {
+				t.Errorf("ShouldRemindToday() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/clock"
+)
+
+// SettingAutoRenewPolicy 自动续订补齐策略的设置项
+const SettingAutoRenewPolicy = "auto_renew_policy"
+
+// 自动续订补齐策略：订阅过期多个周期（如服务停机期间）后如何续订
+const (
+	AutoRenewBackfill = "backfill" // 从原到期日起逐个周期补齐，每个错过的周期写入一条续订记录
+	AutoRenewRebase   = "rebase"   // 从今天起续订一个周期，账单日随之变更
+)
+
+// Renew 从 base 起手动续订一个周期，续订时间为当前时间，详见 RenewAt
+func (s *Subscription) Renew(tx *gorm.DB, base time.Time) (*SubscriptionRenewal, error) {
+	return s.RenewAt(tx, base, clock.Now(), false)
+}
+
+// RenewAt 从 base 起续订一个周期，仅适用于周期订阅：更新到期日期和续订次数，重置提醒暂停与确认状态，
+// 并写入续订时间为 renewedAt 的续订记录，auto 表示由自动续订产生；需在事务中调用，调用方负责加锁读取订阅
+func (s *Subscription) RenewAt(tx *gorm.DB, base, renewedAt time.Time, auto bool) (*SubscriptionRenewal, error) {
+	if !s.IsRecurring() {
+		return nil, ErrNotRenewable
+	}
+	if s.CycleValue <= 0 {
+		s.CycleValue = 1
+	}
+
+	oldExpireDate := s.ExpireDate
+	newExpireDate, ok := s.NextCycleDate(base)
+	if !ok {
+		return nil, ErrCycleExhausted
+	}
+	newRenewCount := s.RenewCount + 1
+	// 从非账单日（如过期后从今天）续订时，账单日随之变更
+	anchorDay := s.BillingDay(base)
+
+	// 进入新周期，清除暂停与确认状态
+	if err := tx.Model(s).Updates(map[string]interface{}{
+		"expire_date":  newExpireDate,
+		"renew_count":  newRenewCount,
+		"anchor_day":   anchorDay,
+		"snooze_until": nil,
+		"acked_at":     nil,
+		"acked_expire": nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	renewal := &SubscriptionRenewal{
+		SubscriptionID: s.ID,
+		RenewedAt:      renewedAt,
+		OldExpireDate:  oldExpireDate,
+		NewExpireDate:  newExpireDate,
+		RenewCount:     newRenewCount,
+		Amount:         s.Amount,
+		Currency:       s.Currency,
+		Auto:           auto,
+	}
+	if err := tx.Create(renewal).Error; err != nil {
+		return nil, err
+	}
+
+	s.ExpireDate = newExpireDate
+	s.RenewCount = newRenewCount
+	s.AnchorDay = anchorDay
+	s.SnoozeUntil = nil
+	s.AckedAt = nil
+	s.AckedExpire = nil
+	return renewal, nil
+}
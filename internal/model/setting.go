@@ -0,0 +1,60 @@
+package model
+
+import "sync"
+
+// settingCache 设置读取缓存，首次读取时加载全部设置，修改后丢弃缓存，下次读取时重新加载
+// 实例所有者的时区一同缓存，修改个人时区或恢复用户后同样需要丢弃缓存
+var settingCache struct {
+	sync.RWMutex
+	values     map[string]string
+	owner      *string // 所有者时区，nil 表示尚未加载
+	generation uint64  // 每次丢弃缓存时递增，避免加载期间被修改的旧值写回缓存
+}
+
+// LookupSetting 从缓存读取设置值，未设置时返回 false
+func LookupSetting(key string) (string, bool) {
+	settingCache.RLock()
+	values := settingCache.values
+	settingCache.RUnlock()
+	if values == nil {
+		values = loadSettings()
+	}
+	v, ok := values[key]
+	return v, ok
+}
+
+// loadSettings 从数据库加载全部设置到缓存，加载失败时不缓存
+func loadSettings() map[string]string {
+	settingCache.RLock()
+	generation := settingCache.generation
+	settingCache.RUnlock()
+
+	db := GetDB()
+	if db == nil {
+		return map[string]string{}
+	}
+	var settings []Setting
+	if err := db.Find(&settings).Error; err != nil {
+		return map[string]string{}
+	}
+	values := make(map[string]string, len(settings))
+	for _, s := range settings {
+		values[s.Key] = s.Value
+	}
+
+	settingCache.Lock()
+	if settingCache.generation == generation {
+		settingCache.values = values
+	}
+	settingCache.Unlock()
+	return values
+}
+
+// InvalidateSettings 丢弃设置缓存，修改设置表（保存设置、恢复备份或快照）后调用
+func InvalidateSettings() {
+	settingCache.Lock()
+	settingCache.values = nil
+	settingCache.owner = nil
+	settingCache.generation++
+	settingCache.Unlock()
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"subdock/internal/clock"
+)
+
+// SettingTimezone 全局时区设置项，IANA 时区名称，如 Asia/Shanghai
+const SettingTimezone = "timezone"
+
+// ValidateTimezone 校验时区名称，空字符串表示继承上一级时区
+func ValidateTimezone(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("无效的时区: %s", name)
+	}
+	return nil
+}
+
+// GlobalLocation 全局时区，未配置或配置无效时使用服务器本地时区（TZ 环境变量）
+func GlobalLocation() *time.Location {
+	if name, ok := LookupSetting(SettingTimezone); ok {
+		if loc := loadLocation(name); loc != nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// ResolveLocation 按顺序返回第一个有效的时区，均未配置时使用全局时区
+func ResolveLocation(names ...string) *time.Location {
+	for _, name := range names {
+		if loc := loadLocation(name); loc != nil {
+			return loc
+		}
+	}
+	return GlobalLocation()
+}
+
+// loadLocation 加载时区，名称为空或无效时返回 nil
+func loadLocation(name string) *time.Location {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// CalendarDate 取 t 在其所属时区下的日历日期，以 UTC 零点表示
+// 到期日等日期字段统一按此形式存储和比较，不随时区换算发生偏移
+func CalendarDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Today 返回 loc 时区下的今天
+func Today(loc *time.Location) time.Time {
+	return CalendarDate(clock.Now().In(loc))
+}
+
+// OwnerTimezone 实例所有者（最早创建的管理员）的个人时区，提醒发送给所有者，未设置时返回空字符串
+func OwnerTimezone() string {
+	settingCache.RLock()
+	owner, generation := settingCache.owner, settingCache.generation
+	settingCache.RUnlock()
+	if owner != nil {
+		return *owner
+	}
+
+	db := GetDB()
+	if db == nil {
+		return ""
+	}
+	var admins []Admin
+	if err := db.Order("id asc").Limit(1).Find(&admins).Error; err != nil {
+		return ""
+	}
+	timezone := ""
+	if len(admins) > 0 {
+		timezone = admins[0].Timezone
+	}
+
+	settingCache.Lock()
+	if settingCache.generation == generation {
+		settingCache.owner = &timezone
+	}
+	settingCache.Unlock()
+	return timezone
+}
+
+// OwnerLocation 实例所有者使用的时区，未单独设置时使用全局时区
+func OwnerLocation() *time.Location {
+	return ResolveLocation(OwnerTimezone())
+}
+
+// Location 订阅使用的时区，未单独设置时依次使用所有者时区和全局时区
+func (s *Subscription) Location() *time.Location {
+	return ResolveLocation(s.Timezone, OwnerTimezone())
+}
+
+// Today 订阅所在时区下的今天
+func (s *Subscription) Today() time.Time {
+	return Today(s.Location())
+}
+
+// Location 用户使用的时区，未单独设置时使用全局时区
+func (a *Admin) Location() *time.Location {
+	return ResolveLocation(a.Timezone)
+}
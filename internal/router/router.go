@@ -3,11 +3,12 @@ package router
 import (
 	"subdock/internal/handler"
 	"subdock/internal/middleware"
+	"subdock/internal/scheduler"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Setup() *gin.Engine {
+func Setup(sched *scheduler.Scheduler) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 
@@ -15,22 +16,68 @@ func Setup() *gin.Engine {
 	{
 		api.GET("/config", handler.GetPublicConfig)
 		api.POST("/login", handler.Login)
+		api.GET("/calendar.ics", handler.CalendarFeed)
+		api.GET("/reminders/:id/:action", handler.ReminderLinkPage)
+		api.POST("/reminders/:id/:action", handler.ReminderLinkAction)
 
 		auth := api.Group("")
 		auth.Use(middleware.AuthRequired())
 		{
 			auth.POST("/change-password", handler.ChangePassword)
+			auth.GET("/profile", handler.GetProfile)
+			auth.PUT("/profile", handler.UpdateProfile)
 
 			auth.GET("/subscriptions", handler.ListSubscriptions)
 			auth.POST("/subscriptions", handler.CreateSubscription)
+			auth.POST("/subscriptions/bulk", handler.BulkSubscriptions)
+			auth.GET("/subscriptions/export", handler.ExportSubscriptions)
+			auth.POST("/subscriptions/import/preview", handler.PreviewImportSubscriptions)
+			auth.POST("/subscriptions/import", handler.ImportSubscriptions)
 			auth.PUT("/subscriptions/:id", handler.UpdateSubscription)
 			auth.POST("/subscriptions/:id/renew", handler.RenewSubscription)
 			auth.DELETE("/subscriptions/:id", handler.DeleteSubscription)
 			auth.POST("/subscriptions/:id/test-notify", handler.TestSubscriptionNotify)
+			auth.POST("/subscriptions/:id/acknowledge", handler.AcknowledgeSubscription)
+			auth.DELETE("/subscriptions/:id/acknowledge", handler.UnacknowledgeSubscription)
+			auth.POST("/subscriptions/:id/snooze", handler.SnoozeSubscription)
+			auth.DELETE("/subscriptions/:id/snooze", handler.UnsnoozeSubscription)
+
+			auth.GET("/categories", handler.ListCategories)
+			auth.POST("/categories", handler.CreateCategory)
+			auth.PUT("/categories/:id", handler.UpdateCategory)
+			auth.DELETE("/categories/:id", handler.DeleteCategory)
+			auth.GET("/tags", handler.ListTags)
+			auth.DELETE("/tags/:id", handler.DeleteTag)
+
+			auth.GET("/calendar/token", handler.GetCalendarToken)
+			auth.POST("/calendar/token/reset", handler.ResetCalendarToken)
+
+			auth.GET("/stats", handler.GetStats)
+			auth.GET("/forecast", handler.GetForecast)
+
+			auth.GET("/budgets", handler.ListBudgets)
+			auth.POST("/budgets", handler.CreateBudget)
+			auth.PUT("/budgets/:id", handler.UpdateBudget)
+			auth.DELETE("/budgets/:id", handler.DeleteBudget)
 
 			auth.GET("/settings", handler.GetSettings)
 			auth.PUT("/settings", handler.UpdateSettings)
 			auth.POST("/settings/test-notify", handler.TestNotify)
+			auth.POST("/settings/notify-schedule/preview", handler.PreviewNotifySchedule)
+
+			auth.GET("/rates", handler.ListRates)
+			auth.POST("/rates/refresh", handler.RefreshRates)
+
+			auth.GET("/backup/export", handler.ExportBackup)
+			auth.POST("/backup/restore", handler.RestoreBackup)
+			auth.GET("/backup/snapshots", handler.ListSnapshots)
+			auth.POST("/backup/snapshots", handler.CreateSnapshot)
+			auth.GET("/backup/snapshots/:name", handler.DownloadSnapshot)
+			auth.POST("/backup/snapshots/:name/restore", handler.RestoreSnapshot)
+			auth.DELETE("/backup/snapshots/:name", handler.DeleteSnapshot)
+
+			auth.GET("/admin/jobs", handler.ListJobs(sched))
+			auth.POST("/admin/jobs/:name/run", handler.RunJob(sched))
 		}
 	}
 
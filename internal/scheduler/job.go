@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+// 定时任务名称
+const (
+	JobCheckNotify  = "check_notify"  // 自动续订、到期提醒与预算告警
+	JobRefreshRates = "refresh_rates" // 刷新汇率
+	JobBackup       = "backup"        // 数据库快照
+	JobDigest       = "digest"        // 通知摘要
+)
+
+// 任务执行结果
+const (
+	JobResultSuccess = "success"
+	JobResultFailed  = "failed"
+)
+
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrJobRunning 任务正在执行，同一任务不并发执行
+	ErrJobRunning = errors.New("任务正在执行")
+)
+
+// job 已注册的定时任务
+type job struct {
+	name        string
+	description string
+	spec        string
+	run         func() error
+	entryID     cron.EntryID
+	mu          sync.Mutex // 保证同一任务不并发执行
+	running     atomic.Bool
+}
+
+// JobStatus 任务状态
+type JobStatus struct {
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Schedule      string     `json:"schedule"` // cron 表达式
+	Running       bool       `json:"running"`
+	LastRunAt     *time.Time `json:"last_run_at"`     // 从未执行时为 null
+	LastSuccessAt *time.Time `json:"last_success_at"` // 从未成功执行时为 null
+	DurationMs    int64      `json:"duration_ms"`
+	Result        string     `json:"result"` // success 或 failed，从未执行时为空
+	Error         string     `json:"error"`
+	NextRunAt     *time.Time `json:"next_run_at"` // 调度器未启动时为 null
+}
+
+// register 注册定时任务并加入 cron 调度
+func (s *Scheduler) register(name, description, spec string, run func() error) {
+	j := &job{name: name, description: description, spec: spec, run: run}
+	id, err := s.cron.AddFunc(spec, func() {
+		if err := s.runJob(j); err != nil && !errors.Is(err, ErrJobRunning) {
+			log.Printf("定时任务 %s 执行失败: %v", name, err)
+		}
+	})
+	if err != nil {
+		log.Printf("注册定时任务 %s 失败: %v", name, err)
+		return
+	}
+	j.entryID = id
+	s.jobs = append(s.jobs, j)
+}
+
+// findJob 按名称查找任务
+func (s *Scheduler) findJob(name string) *job {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// runJob 执行任务并记录执行结果，任务正在执行时返回 ErrJobRunning
+func (s *Scheduler) runJob(j *job) error {
+	if !j.mu.TryLock() {
+		return ErrJobRunning
+	}
+	defer j.mu.Unlock()
+	j.running.Store(true)
+	defer j.running.Store(false)
+
+	start := clock.Now()
+	err := j.run()
+	run := model.JobRun{
+		Name:       j.name,
+		LastRunAt:  start,
+		DurationMs: clock.Now().Sub(start).Milliseconds(),
+		Result:     JobResultSuccess,
+	}
+	columns := []string{"last_run_at", "duration_ms", "result", "error"}
+	if err != nil {
+		run.Result = JobResultFailed
+		run.Error = err.Error()
+	} else {
+		run.LastSuccessAt = &start
+		columns = append(columns, "last_success_at")
+	}
+	if dbErr := model.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&run).Error; dbErr != nil {
+		log.Printf("记录定时任务 %s 执行结果失败: %v", j.name, dbErr)
+	}
+	return err
+}
+
+// lastRunAt 任务最近一次执行的开始时间，从未执行时返回零值
+func lastRunAt(name string) time.Time {
+	var run model.JobRun
+	if err := model.GetDB().Where("name = ?", name).Limit(1).Find(&run).Error; err != nil {
+		return time.Time{}
+	}
+	return run.LastRunAt
+}
+
+// lastSuccessAt 任务最近一次成功执行的开始时间，从未成功执行时返回零值
+func lastSuccessAt(name string) time.Time {
+	var run model.JobRun
+	if err := model.GetDB().Where("name = ?", name).Limit(1).Find(&run).Error; err != nil || run.LastSuccessAt == nil {
+		return time.Time{}
+	}
+	return *run.LastSuccessAt
+}
+
+// RunJob 立即执行指定任务并返回执行后的状态，任务执行失败时同时返回状态和错误
+func (s *Scheduler) RunJob(name string) (*JobStatus, error) {
+	j := s.findJob(name)
+	if j == nil {
+		return nil, ErrJobNotFound
+	}
+	err := s.runJob(j)
+	if errors.Is(err, ErrJobRunning) {
+		return nil, err
+	}
+	status := s.jobStatus(j)
+	return &status, err
+}
+
+// Jobs 返回所有任务的状态，按注册顺序排列
+func (s *Scheduler) Jobs() []JobStatus {
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, s.jobStatus(j))
+	}
+	return statuses
+}
+
+// jobStatus 组合任务的调度信息与最近一次执行记录
+func (s *Scheduler) jobStatus(j *job) JobStatus {
+	status := JobStatus{
+		Name:        j.name,
+		Description: j.description,
+		Schedule:    j.spec,
+		Running:     j.running.Load(),
+	}
+
+	var run model.JobRun
+	if model.GetDB().Where("name = ?", j.name).Limit(1).Find(&run).RowsAffected > 0 {
+		lastRun := run.LastRunAt
+		status.LastRunAt = &lastRun
+		status.LastSuccessAt = run.LastSuccessAt
+		status.DurationMs = run.DurationMs
+		status.Result = run.Result
+		status.Error = run.Error
+	}
+
+	for _, entry := range s.cron.Entries() {
+		if entry.ID == j.entryID && !entry.Next.IsZero() {
+			next := entry.Next
+			status.NextRunAt = &next
+		}
+	}
+	return status
+}
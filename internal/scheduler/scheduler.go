@@ -1,15 +1,16 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm/clause"
 
+	"subdock/internal/clock"
 	"subdock/internal/model"
 	"subdock/internal/service"
 )
@@ -18,174 +19,477 @@ import (
 type Scheduler struct {
 	cron     *cron.Cron
 	notifier *service.Notifier
+	jobs     []*job
+	ctx      context.Context
+	cancel   context.CancelFunc
 }
 
 // New 创建调度器
 func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Scheduler{
 		cron:     cron.New(),
 		notifier: service.NewNotifier(),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 }
 
-// Start 启动调度器
+// Start 启动调度器，并补做停机期间错过的任务
 func (s *Scheduler) Start() {
-	// 每小时检查一次
-	s.cron.AddFunc("0 * * * *", s.checkAndNotify)
+	// 每分钟检查一次，是否发送由通知计划 notify_schedule 决定
+	s.register(JobCheckNotify, "自动续订、到期提醒与预算告警", "* * * * *", s.checkAndNotify)
+	// 每 6 小时刷新一次汇率
+	s.register(JobRefreshRates, "刷新汇率", "20 */6 * * *", s.refreshRates)
+	// 每天 03:30 生成数据库快照
+	s.register(JobBackup, "数据库快照", "30 3 * * *", s.backupDatabase)
+	// 每分钟检查一次，是否发送由摘要计划 digest_schedule 决定
+	s.register(JobDigest, "通知摘要", "* * * * *", s.sendDigest)
 	s.cron.Start()
+	go s.catchUp()
+	// Telegram 机器人长轮询
+	go s.runTelegramBot()
 	log.Println("调度器已启动")
 }
 
+// catchUp 启动时补做停机期间错过的任务：立即续订已到期的自动续订订阅，
+// 并执行上次执行后已错过调度时间的任务；从未执行过的任务按正常调度执行
+func (s *Scheduler) catchUp() {
+	if err := s.renewOverdue(); err != nil {
+		log.Printf("补做自动续订失败: %v", err)
+	}
+
+	now := clock.Now()
+	for _, j := range s.jobs {
+		last := lastRunAt(j.name)
+		if last.IsZero() {
+			continue
+		}
+		schedule, err := cron.ParseStandard(j.spec)
+		if err != nil || schedule.Next(last).After(now) {
+			continue
+		}
+		log.Printf("补做错过的定时任务: %s", j.name)
+		if err := s.runJob(j); err != nil && !errors.Is(err, ErrJobRunning) {
+			log.Printf("定时任务 %s 执行失败: %v", j.name, err)
+		}
+	}
+}
+
+// renewOverdue 续订所有已到期的自动续订订阅，不受通知时段限制
+func (s *Scheduler) renewOverdue() error {
+	var ids []uint
+	if err := model.GetDB().Model(&model.Subscription{}).
+		Where("status = ? AND auto_renew = ?", model.StatusActive, true).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	failed := 0
+	for _, id := range ids {
+		if _, err := s.autoRenewIfNeeded(id); err != nil {
+			log.Printf("自动续订失败(订阅ID=%d): %v", id, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个订阅自动续订失败", failed)
+	}
+	return nil
+}
+
 // Stop 停止调度器
 func (s *Scheduler) Stop() {
+	s.cancel()
 	s.cron.Stop()
 }
 
 // checkAndNotify 检查并发送到期提醒
-func (s *Scheduler) checkAndNotify() {
-	currentHour := time.Now().Hour()
-
-	// 获取通知时段配置
-	notifyHours := getSetting("notify_hours", "9")
-	hours := parseNotifyHours(notifyHours)
-
-	// 检查当前小时是否在通知时段内
-	shouldNotify := false
-	for _, h := range hours {
-		if h == currentHour {
-			shouldNotify = true
-			break
-		}
+// 每个订阅按其时区判断今天的通知计划时间是否落在上次执行之后，预算告警按所有者时区判断；
+// 自动续订按计划时间执行，提醒与告警在免打扰时段内推迟到时段结束；
+// 从未执行过时只检查最近一分钟，手动执行或补做时不会重复发送已发送过的提醒；
+// 自动续订从上次成功执行起计算，续订失败的订阅在之后每次执行时重试，直到续订成功
+func (s *Scheduler) checkAndNotify() error {
+	now := clock.Now()
+	since := windowStart(lastRunAt(JobCheckNotify), now)
+	renewSince := since
+	if last := lastSuccessAt(JobCheckNotify); !last.IsZero() && last.Before(since) {
+		renewSince = last
 	}
 
-	if !shouldNotify {
-		return
+	// 获取通知计划与免打扰时段配置
+	expr := service.EffectiveNotifySchedule(service.GetSetting(service.SettingNotifySchedule), service.GetSetting(service.SettingNotifyHours))
+	schedule, err := service.ParseNotifySchedule(expr)
+	if err != nil {
+		return err
+	}
+	quiet, err := service.ParseQuietHours(service.GetSetting(service.SettingQuietHours))
+	if err != nil {
+		log.Printf("免打扰时段配置无效，已忽略: %v", err)
+		quiet = nil
 	}
 
-	// 获取需要提醒的订阅
+	// 获取需要提醒的订阅（已暂停或已取消的订阅不再提醒和自动续订）
 	var subscriptions []model.Subscription
-	if err := model.GetDB().Find(&subscriptions).Error; err != nil {
-		log.Printf("获取订阅列表失败: %v", err)
-		return
+	if err := model.GetDB().Where("status = ?", model.StatusActive).Find(&subscriptions).Error; err != nil {
+		return fmt.Errorf("获取订阅列表失败: %w", err)
+	}
+
+	digestMode := service.GetSetting(service.SettingNotifyMode) == service.NotifyModeDigest
+	overduePolicy := model.GlobalOverduePolicy()
+
+	// 同一时区的判断结果相同，按时区缓存
+	renewDue, notifyDue := make(map[string]bool), make(map[string]bool)
+	due := func(cache map[string]bool, q *service.QuietHours, from time.Time, loc *time.Location) bool {
+		v, ok := cache[loc.String()]
+		if !ok {
+			v = service.NotifyDueBetween(schedule, q, from, now, loc)
+			cache[loc.String()] = v
+		}
+		return v
 	}
 
+	failed := 0
 	for _, sub := range subscriptions {
-		if sub.AutoRenew {
+		loc := sub.Location()
+		if sub.AutoRenew && due(renewDue, nil, renewSince, loc) {
 			renewed, err := s.autoRenewIfNeeded(sub.ID)
 			if err != nil {
 				log.Printf("自动续订失败(订阅ID=%d): %v", sub.ID, err)
-			} else if renewed {
+				failed++
+			} else if renewed > 0 {
 				if err := model.GetDB().First(&sub, sub.ID).Error; err != nil {
 					log.Printf("自动续订后刷新订阅失败(订阅ID=%d): %v", sub.ID, err)
 				}
 			}
 		}
 
+		if !due(notifyDue, quiet, since, loc) {
+			continue
+		}
+		// 摘要模式下到期与过期提醒汇总在摘要中发送，过期升级仍单独发送到升级通知渠道
+		if digestMode {
+			if overdue, ok := sub.OverdueReminderOn(sub.Today(), overduePolicy); ok && overdue.Escalate {
+				s.escalate(overdueNotification(sub, overdue))
+			}
+			continue
+		}
 		if sub.ShouldRemindToday() {
 			s.sendNotification(sub)
+		} else if overdue, ok := sub.OverdueReminderOn(sub.Today(), overduePolicy); ok {
+			s.sendOverdueNotification(sub, overdue)
 		}
 	}
+
+	if due(notifyDue, quiet, since, model.OwnerLocation()) {
+		s.checkBudgets()
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个订阅自动续订失败", failed)
+	}
+	return nil
+}
+
+// sendDigest 摘要模式下按摘要计划向所有通知渠道发送一条摘要
+// 摘要计划按所有者时区计算，免打扰时段内推迟到时段结束；从上次成功执行起计算，生成失败时下次执行重试，从未成功执行过时只检查最近一分钟
+func (s *Scheduler) sendDigest() error {
+	if service.GetSetting(service.SettingNotifyMode) != service.NotifyModeDigest {
+		return nil
+	}
+	now := clock.Now()
+	since := windowStart(lastSuccessAt(JobDigest), now)
+
+	cadence := service.GetSetting(service.SettingDigestCadence)
+	schedule, err := service.ParseNotifySchedule(service.EffectiveDigestSchedule(service.GetSetting(service.SettingDigestSchedule), cadence))
+	if err != nil {
+		return err
+	}
+	quiet, err := service.ParseQuietHours(service.GetSetting(service.SettingQuietHours))
+	if err != nil {
+		log.Printf("免打扰时段配置无效，已忽略: %v", err)
+		quiet = nil
+	}
+	loc := model.OwnerLocation()
+	if !service.NotifyDueBetween(schedule, quiet, since, now, loc) {
+		return nil
+	}
+
+	days, err := service.ParseDigestDays(service.GetSetting(service.SettingDigestDays))
+	if err != nil {
+		days = service.DefaultDigestDays
+	}
+	local := now.In(loc)
+	digest, err := service.BuildDigest(cadence, local, local.Add(-service.DigestLookback(cadence)), days, service.GetSetting(service.SettingBaseCurrency))
+	if err != nil {
+		return fmt.Errorf("生成摘要失败: %w", err)
+	}
+	s.broadcast(service.Notification{Event: service.NotifyEventDigest, Title: "订阅摘要", Message: service.FormatDigest(digest)})
+	return nil
 }
 
-// autoRenewIfNeeded 当启用自动续订且已到期时自动续订 1 次
-func (s *Scheduler) autoRenewIfNeeded(subscriptionID uint) (bool, error) {
+// windowStart 本次检查窗口的起点，last 为零值或不早于 now 时只检查最近一分钟
+func windowStart(last, now time.Time) time.Time {
+	if last.IsZero() || !last.Before(now) {
+		return now.Add(-time.Minute)
+	}
+	return last
+}
+
+// checkBudgets 检查预算执行情况，越过阈值时发送告警
+func (s *Scheduler) checkBudgets() {
+	now := clock.Now().In(model.OwnerLocation())
+	statuses, err := service.BudgetStatuses(now)
+	if err != nil {
+		log.Printf("计算预算失败: %v", err)
+		return
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		alerts, err := service.PendingBudgetAlerts(status, now)
+		if err != nil {
+			log.Printf("检查预算告警失败(预算ID=%d): %v", status.Budget.ID, err)
+			continue
+		}
+		// 至少一个通知渠道发送成功后才记录告警，全部失败时下次检查重试
+		for _, alert := range alerts {
+			if !s.broadcast(service.Notification{Event: service.NotifyEventBudget, Title: "预算提醒", Message: service.FormatBudgetAlert(status, alert)}) {
+				continue
+			}
+			if err := service.RecordBudgetAlert(&alert); err != nil {
+				log.Printf("记录预算告警失败(预算ID=%d): %v", status.Budget.ID, err)
+			}
+		}
+	}
+}
+
+// maxCatchUpCycles 单次补齐的最大周期数，剩余周期在下次检查时继续补齐
+const maxCatchUpCycles = 1000
+
+// autoRenewIfNeeded 当启用自动续订且已到期时自动续订，返回续订的周期数
+// 过期多个周期时按 auto_renew_policy 处理：backfill（默认）从原到期日起逐个周期补齐，
+// 补齐的续订记录以该周期的到期日作为续订时间；rebase 从今天起续订一个周期
+func (s *Scheduler) autoRenewIfNeeded(subscriptionID uint) (int, error) {
 	tx := model.GetDB().Begin()
 	if tx.Error != nil {
-		return false, tx.Error
+		return 0, tx.Error
 	}
 
 	var subscription model.Subscription
 	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&subscription, subscriptionID).Error; err != nil {
 		tx.Rollback()
-		return false, err
+		return 0, err
 	}
 
-	today := time.Now().Truncate(24 * time.Hour)
-	expire := subscription.ExpireDate.Truncate(24 * time.Hour)
-	if !subscription.AutoRenew || expire.After(today) {
+	today := subscription.Today()
+	expire := model.CalendarDate(subscription.ExpireDate)
+	if !subscription.AutoRenew || !subscription.IsRecurring() || expire.After(today) {
 		tx.Rollback()
-		return false, nil
+		return 0, nil
 	}
 
-	oldExpireDate := subscription.ExpireDate
-	base := subscription.ExpireDate
-	if base.Before(today) {
-		base = today
+	renewed := 0
+	if service.GetSetting(model.SettingAutoRenewPolicy) == model.AutoRenewRebase {
+		base := subscription.ExpireDate
+		if base.Before(today) {
+			base = today
+		}
+		if _, err := subscription.RenewAt(tx, base, clock.Now(), true); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		renewed = 1
+	} else {
+		for ; renewed < maxCatchUpCycles && !expire.After(today); renewed++ {
+			renewedAt := clock.Now()
+			if expire.Before(today) {
+				renewedAt = expire
+			}
+			_, err := subscription.RenewAt(tx, subscription.ExpireDate, renewedAt, true)
+			// 规则周期已用尽时保留已补齐的周期
+			if errors.Is(err, model.ErrCycleExhausted) && renewed > 0 {
+				break
+			}
+			if err != nil {
+				tx.Rollback()
+				return 0, err
+			}
+			expire = model.CalendarDate(subscription.ExpireDate)
+		}
 	}
-	newExpireDate := subscription.CalculateExpireDateFrom(base)
-	newRenewCount := subscription.RenewCount + 1
 
-	if err := tx.Model(&subscription).Updates(map[string]interface{}{
-		"expire_date": newExpireDate,
-		"renew_count": newRenewCount,
-	}).Error; err != nil {
-		tx.Rollback()
-		return false, err
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
 	}
 
-	renewal := &model.SubscriptionRenewal{
-		SubscriptionID: subscription.ID,
-		RenewedAt:      time.Now(),
-		OldExpireDate:  oldExpireDate,
-		NewExpireDate:  newExpireDate,
-		RenewCount:     newRenewCount,
+	return renewed, nil
+}
+
+// refreshRates 从配置的汇率源刷新汇率，未配置汇率源时跳过
+func (s *Scheduler) refreshRates() error {
+	kind := service.GetSetting(service.SettingRateProvider)
+	if kind == "" {
+		return nil
 	}
-	if err := tx.Create(renewal).Error; err != nil {
-		tx.Rollback()
-		return false, err
+
+	provider, err := service.NewRateProvider(kind, service.GetSetting(service.SettingRateSourceURL))
+	if err != nil {
+		return fmt.Errorf("创建汇率源失败: %w", err)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return false, err
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	count, err := service.RefreshRates(ctx, provider)
+	if err != nil {
+		return fmt.Errorf("刷新汇率失败: %w", err)
+	}
+	log.Printf("汇率已刷新: %s, %d 条", provider.Name(), count)
+	return nil
+}
+
+// backupDatabase 生成数据库快照并按保留策略清理旧快照
+func (s *Scheduler) backupDatabase() error {
+	if !service.GetSettingBool(service.SettingBackupEnabled) {
+		return nil
 	}
 
-	return true, nil
+	snapshot, err := service.CreateSnapshot("")
+	if err != nil {
+		return fmt.Errorf("生成数据库快照失败: %w", err)
+	}
+	log.Printf("已生成数据库快照: %s", snapshot.Name)
+
+	retention := service.ParseSnapshotRetention(
+		service.GetSetting(service.SettingBackupKeepLast),
+		service.GetSetting(service.SettingBackupKeepDaily),
+		service.GetSetting(service.SettingBackupKeepWeekly),
+	)
+	removed, err := service.RotateSnapshots(retention)
+	if len(removed) > 0 {
+		log.Printf("已清理 %d 个旧快照", len(removed))
+	}
+	if err != nil {
+		return fmt.Errorf("清理旧快照失败: %w", err)
+	}
+	return nil
 }
 
-// sendNotification 发送订阅到期提醒
-func (s *Scheduler) sendNotification(sub model.Subscription) {
-	daysLeft := int(time.Until(sub.ExpireDate).Hours() / 24)
-	message := fmt.Sprintf("📢 订阅到期提醒\n\n订阅名称: %s\n金额: %.2f %s\n到期日期: %s\n剩余天数: %d 天",
-		sub.Name, sub.Amount, sub.Currency, sub.ExpireDate.Format("2006-01-02"), daysLeft)
+// runTelegramBot 以长轮询方式接收 Telegram 命令，每轮重新读取设置，修改配置后无需重启
+func (s *Scheduler) runTelegramBot() {
+	var offset int64
+	for s.ctx.Err() == nil {
+		token := service.GetSetting(service.SettingTelegramBotToken)
+		if !service.GetSettingBool(service.SettingTelegramBotEnabled) || token == "" {
+			s.sleep(30 * time.Second)
+			continue
+		}
 
-	// 尝试 Telegram 通知
-	telegramToken := getSetting("telegram_bot_token", "")
-	telegramChatID := getSetting("telegram_chat_id", "")
-	if telegramToken != "" && telegramChatID != "" {
-		if err := s.notifier.SendTelegram(telegramToken, telegramChatID, message); err != nil {
-			log.Printf("发送 Telegram 通知失败: %v", err)
+		bot := service.NewTelegramBot(
+			service.NewTelegramClient(service.GetSetting(service.SettingTelegramAPIURL), token),
+			service.ParseChatIDs(service.GetSetting(service.SettingTelegramChatID), service.GetSetting(service.SettingTelegramAllowedChatIDs)),
+			service.GetSetting(service.SettingBaseCurrency),
+		)
+		next, err := bot.Poll(s.ctx, offset)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Printf("获取 Telegram 更新失败: %v", err)
+				s.sleep(10 * time.Second)
+			}
+			continue
 		}
+		offset = next
 	}
+}
 
-	// 尝试 Bark 通知
-	barkURL := getSetting("bark_url", "")
-	if barkURL != "" {
-		if err := s.notifier.SendBark(barkURL, "订阅到期提醒", message); err != nil {
-			log.Printf("发送 Bark 通知失败: %v", err)
+// sleep 等待 d 或调度器停止
+func (s *Scheduler) sleep(d time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// sendNotification 发送订阅到期提醒，命中支持/更新截止日期时发送支持到期提醒
+func (s *Scheduler) sendNotification(sub model.Subscription) {
+	date, offset, _ := sub.RemindTargetOn(sub.Today())
+	event, title, dateLabel := service.NotifyEventExpire, "订阅到期提醒", "到期日期"
+	if sub.IsSupportEnd(date) {
+		event, title, dateLabel = service.NotifyEventSupportEnd, "支持/更新到期提醒", "支持截止日期"
+	}
+	remaining := fmt.Sprintf("剩余天数: %d 天", offset)
+	switch {
+	case offset == 0:
+		remaining = "今天到期"
+	case offset < 0:
+		remaining = fmt.Sprintf("已过期 %d 天", -offset)
+		if event == service.NotifyEventExpire {
+			event = service.NotifyEventExpired
 		}
 	}
+	message := fmt.Sprintf("📢 %s\n\n订阅名称: %s\n金额: %.2f %s\n%s: %s\n%s",
+		title, sub.Name, sub.Amount, sub.Currency, dateLabel, date.Format("2006-01-02"), remaining)
+	message += service.ReminderLinksText(service.GetSetting(service.SettingPublicURL), sub.ID, clock.Now())
+
+	s.broadcast(service.Notification{Event: event, Title: title, Message: message, Keyboard: service.ReminderKeyboard(sub.ID)})
 }
 
-// parseNotifyHours 解析通知时段配置
-func parseNotifyHours(s string) []int {
-	var hours []int
-	parts := strings.Split(s, ",")
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if h, err := strconv.Atoi(p); err == nil && h >= 1 && h <= 24 {
-			hours = append(hours, h%24)
+// sendOverdueNotification 发送过期提醒，过期天数达到 overdue_escalate_after 时同时发送到升级通知渠道
+func (s *Scheduler) sendOverdueNotification(sub model.Subscription, overdue model.OverdueReminder) {
+	n := overdueNotification(sub, overdue)
+	s.broadcast(n)
+	if overdue.Escalate {
+		s.escalate(n)
+	}
+}
+
+// overdueNotification 生成过期提醒
+func overdueNotification(sub model.Subscription, overdue model.OverdueReminder) service.Notification {
+	title := "订阅已过期"
+	message := fmt.Sprintf("⚠️ %s\n\n订阅名称: %s\n金额: %.2f %s\n到期日期: %s\n已过期 %d 天（第 %d 次过期提醒）",
+		title, sub.Name, sub.Amount, sub.Currency, sub.ExpireDate.Format("2006-01-02"), overdue.Days, overdue.Count)
+	message += service.ReminderLinksText(service.GetSetting(service.SettingPublicURL), sub.ID, clock.Now())
+	return service.Notification{Event: service.NotifyEventExpired, Title: title, Message: message, Keyboard: service.ReminderKeyboard(sub.ID)}
+}
+
+// escalate 向升级通知渠道发送通知，标题加“【升级】”前缀；未配置升级渠道时不发送
+func (s *Scheduler) escalate(n service.Notification) {
+	n.Title = "【升级】" + n.Title
+	telegramToken := service.GetSetting(service.SettingTelegramBotToken)
+	chatID := service.GetSetting(model.SettingOverdueEscalateTelegramChatID)
+	if telegramToken != "" && chatID != "" {
+		if err := s.notifier.SendTelegram(service.GetSetting(service.SettingTelegramAPIURL), telegramToken, chatID, n.Message, n.Keyboard); err != nil {
+			log.Printf("发送 Telegram 升级通知失败: %v", err)
 		}
 	}
-	if len(hours) == 0 {
-		hours = []int{9}
+
+	if barkURL := service.GetSetting(model.SettingOverdueEscalateBarkURL); barkURL != "" {
+		if err := s.notifier.SendBark(barkURL, n.Title, n.Message, n.Event); err != nil {
+			log.Printf("发送 Bark 升级通知失败: %v", err)
+		}
 	}
-	return hours
 }
 
-func getSetting(key, defaultVal string) string {
-	var setting model.Setting
-	if err := model.GetDB().Where("key = ?", key).First(&setting).Error; err != nil {
-		return defaultVal
+// broadcast 向所有已配置的通知渠道发送通知，返回是否至少一个渠道发送成功
+func (s *Scheduler) broadcast(n service.Notification) bool {
+	sent := false
+	// 尝试 Telegram 通知
+	telegramToken := service.GetSetting(service.SettingTelegramBotToken)
+	telegramChatID := service.GetSetting(service.SettingTelegramChatID)
+	if telegramToken != "" && telegramChatID != "" {
+		if err := s.notifier.SendTelegram(service.GetSetting(service.SettingTelegramAPIURL), telegramToken, telegramChatID, n.Message, n.Keyboard); err != nil {
+			log.Printf("发送 Telegram 通知失败: %v", err)
+		} else {
+			sent = true
+		}
+	}
+
+	// 尝试 Bark 通知
+	barkURL := service.GetSetting(service.SettingBarkURL)
+	if barkURL != "" {
+		if err := s.notifier.SendBark(barkURL, n.Title, n.Message, n.Event); err != nil {
+			log.Printf("发送 Bark 通知失败: %v", err)
+		} else {
+			sent = true
+		}
 	}
-	return setting.Value
+	return sent
 }
@@ -0,0 +1,430 @@
+package scheduler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/clock"
+	"subdock/internal/config"
+	"subdock/internal/model"
+	"subdock/internal/service"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "subdock-scheduler-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("DATA_DIR", dir)
+	config.Load()
+	log.SetOutput(io.Discard)
+	if _, err := model.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// resetSubscriptions 清空订阅与续订记录
+func resetSubscriptions(t *testing.T) {
+	t.Helper()
+	db := model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true})
+	if err := db.Unscoped().Delete(&model.SubscriptionRenewal{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Unscoped().Delete(&model.Subscription{}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createSubscription 创建按月订阅，开始日期为到期日期前一个月
+func createSubscription(t *testing.T, expire string, autoRenew bool, timezone string) *model.Subscription {
+	t.Helper()
+	sub := &model.Subscription{
+		Name:          "test",
+		Amount:        10,
+		Currency:      "CNY",
+		StartDate:     date(expire).AddDate(0, -1, 0),
+		CycleValue:    1,
+		CycleUnit:     model.CycleUnitMonth,
+		ExpireDate:    date(expire),
+		AutoRenew:     autoRenew,
+		RemindOffsets: "0",
+		Status:        model.StatusActive,
+		Timezone:      timezone,
+	}
+	if err := model.GetDB().Create(sub).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func reload(t *testing.T, id uint) model.Subscription {
+	t.Helper()
+	var sub model.Subscription
+	if err := model.GetDB().First(&sub, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// setAutoRenewPolicy 设置自动续订补齐策略，测试结束后恢复默认
+func setAutoRenewPolicy(t *testing.T, policy string) {
+	t.Helper()
+	if err := service.SetSetting(model.SettingAutoRenewPolicy, policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		service.SetSetting(model.SettingAutoRenewPolicy, "")
+	})
+}
+
+func TestAutoRenewIfNeeded(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      string
+		expire      string
+		autoRenew   bool
+		today       string
+		wantRenewed int
+		wantExpire  string
+	}{
+		{"未到期", model.AutoRenewBackfill, "2026-03-21", true, "2026-03-20", 0, "2026-03-21"},
+		{"到期当天从到期日续订", model.AutoRenewBackfill, "2026-03-20", true, "2026-03-20", 1, "2026-04-20"},
+		{"过期多个周期逐个补齐", model.AutoRenewBackfill, "2026-01-10", true, "2026-03-20", 3, "2026-04-10"},
+		{"过期多个周期从今天续订", model.AutoRenewRebase, "2026-01-10", true, "2026-03-20", 1, "2026-04-20"},
+		{"未开启自动续订", model.AutoRenewBackfill, "2026-03-01", false, "2026-03-20", 0, "2026-03-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSubscriptions(t)
+			setAutoRenewPolicy(t, tt.policy)
+			t.Cleanup(clock.Set(clock.NewFixed(date(tt.today).Add(9 * time.Hour))))
+
+			sub := createSubscription(t, tt.expire, tt.autoRenew, "UTC")
+			renewed, err := New().autoRenewIfNeeded(sub.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if renewed != tt.wantRenewed {
+				t.Errorf("renewed = %d, want %d", renewed, tt.wantRenewed)
+			}
+			got := reload(t, sub.ID)
+			if !got.ExpireDate.Equal(date(tt.wantExpire)) {
+				t.Errorf("expire_date = %s, want %s", got.ExpireDate.Format("2006-01-02"), tt.wantExpire)
+			}
+			if got.RenewCount != tt.wantRenewed {
+				t.Errorf("renew_count = %d, want %d", got.RenewCount, tt.wantRenewed)
+			}
+		})
+	}
+}
+
+func TestAutoRenewBackfillRecords(t *testing.T) {
+	resetSubscriptions(t)
+	setAutoRenewPolicy(t, model.AutoRenewBackfill)
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+
+	sub := createSubscription(t, "2026-01-31", true, "UTC")
+	if _, err := New().autoRenewIfNeeded(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var renewals []model.SubscriptionRenewal
+	if err := model.GetDB().Where("subscription_id = ?", sub.ID).Order("renew_count asc").Find(&renewals).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 每个错过的周期一条记录，续订时间为该周期的到期日，账单日保持 31 日
+	want := []struct{ renewedAt, oldExpire, newExpire string }{
+		{"2026-01-31", "2026-01-31", "2026-02-28"},
+		{"2026-02-28", "2026-02-28", "2026-03-31"},
+	}
+	if len(renewals) != len(want) {
+		t.Fatalf("renewals = %d, want %d", len(renewals), len(want))
+	}
+	for i, w := range want {
+		r := renewals[i]
+		if !r.RenewedAt.Equal(date(w.renewedAt)) || !r.OldExpireDate.Equal(date(w.oldExpire)) || !r.NewExpireDate.Equal(date(w.newExpire)) {
+			t.Errorf("renewal[%d] = %s %s→%s, want %s %s→%s", i,
+				r.RenewedAt.Format("2006-01-02"), r.OldExpireDate.Format("2006-01-02"), r.NewExpireDate.Format("2006-01-02"),
+				w.renewedAt, w.oldExpire, w.newExpire)
+		}
+	}
+}
+
+func TestCheckAndNotifyRespectsTimezoneNotifyHour(t *testing.T) {
+	resetSubscriptions(t)
+	// 默认通知时段为 9 点；UTC 01:00 即上海 09:00
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 1, 0, 0, 0, time.UTC))))
+
+	utc := createSubscription(t, "2026-03-19", true, "UTC")
+	shanghai := createSubscription(t, "2026-03-20", true, "Asia/Shanghai")
+
+	New().checkAndNotify()
+
+	if got := reload(t, utc.ID); got.RenewCount != 0 {
+		t.Errorf("UTC 订阅不应在 01:00 自动续订, renew_count = %d", got.RenewCount)
+	}
+	got := reload(t, shanghai.ID)
+	if got.RenewCount != 1 || !got.ExpireDate.Equal(date("2026-04-20")) {
+		t.Errorf("上海订阅应在当地 09:00 自动续订, renew_count = %d, expire_date = %s",
+			got.RenewCount, got.ExpireDate.Format("2006-01-02"))
+	}
+}
+
+func TestCheckAndNotifyFallsBackToOwnerTimezone(t *testing.T) {
+	resetSubscriptions(t)
+	setSettings(t, map[string]string{model.SettingTimezone: "UTC"})
+	setOwnerTimezone(t, "Asia/Shanghai")
+	// UTC 01:00 即上海 09:00，未设置时区的订阅按所有者时区判断
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 1, 0, 0, 0, time.UTC))))
+
+	inherited := createSubscription(t, "2026-03-20", true, "")
+	utc := createSubscription(t, "2026-03-20", true, "UTC")
+
+	New().checkAndNotify()
+
+	if got := reload(t, inherited.ID); got.RenewCount != 1 {
+		t.Errorf("未设置时区的订阅应在所有者时区 09:00 自动续订, renew_count = %d", got.RenewCount)
+	}
+	if got := reload(t, utc.ID); got.RenewCount != 0 {
+		t.Errorf("订阅时区优先于所有者时区, renew_count = %d", got.RenewCount)
+	}
+}
+
+// setOwnerTimezone 设置实例所有者的个人时区，测试结束后清空
+func setOwnerTimezone(t *testing.T, timezone string) {
+	t.Helper()
+	update := func(timezone string) error {
+		defer model.InvalidateSettings()
+		return model.GetDB().Model(&model.Admin{}).Where("1 = 1").Update("timezone", timezone).Error
+	}
+	if err := update(timezone); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { update("") })
+}
+
+// setSettings 保存设置，测试结束后恢复默认
+func setSettings(t *testing.T, values map[string]string) {
+	t.Helper()
+	changes := make(map[string]*string, len(values))
+	for key, value := range values {
+		changes[key] = &value
+	}
+	if _, err := service.UpdateSettings(changes); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for key := range changes {
+			changes[key] = nil
+		}
+		service.UpdateSettings(changes)
+	})
+}
+
+func TestDigestModeEscalatesOverdue(t *testing.T) {
+	resetSubscriptions(t)
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+
+	var groups []string
+	bark := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groups = append(groups, r.URL.Query().Get("group"))
+	}))
+	defer bark.Close()
+	setSettings(t, map[string]string{
+		service.SettingNotifyMode:           service.NotifyModeDigest,
+		model.SettingOverdueEscalateAfter:   "5",
+		model.SettingOverdueEscalateBarkURL: bark.URL,
+	})
+
+	// 过期 6 天：第 2 次过期提醒，已达到升级天数
+	createSubscription(t, "2026-03-14", false, "UTC")
+	if err := New().checkAndNotify(); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0] != service.NotifyEventExpired {
+		t.Errorf("摘要模式下应发送一次过期升级通知, groups = %v", groups)
+	}
+}
+
+func TestCheckBudgetsRecordsAlertAfterDelivery(t *testing.T) {
+	resetSubscriptions(t)
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+
+	status := http.StatusInternalServerError
+	bark := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer bark.Close()
+	setSettings(t, map[string]string{service.SettingBarkURL: bark.URL})
+
+	budget := model.Budget{Name: "test", Period: model.BudgetPeriodMonth, Amount: 5, Currency: "CNY", Thresholds: "100", Enabled: true}
+	if err := model.GetDB().Create(&budget).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		model.GetDB().Where("budget_id = ?", budget.ID).Delete(&model.BudgetAlert{})
+		model.GetDB().Delete(&budget)
+	})
+	// 本月自动续订支出 10，超过预算
+	sub := createSubscription(t, "2026-03-19", true, "UTC")
+	s := New()
+	if _, err := s.autoRenewIfNeeded(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	alerts := func() int64 {
+		var count int64
+		model.GetDB().Model(&model.BudgetAlert{}).Where("budget_id = ?", budget.ID).Count(&count)
+		return count
+	}
+	s.checkBudgets()
+	if got := alerts(); got != 0 {
+		t.Fatalf("发送失败时不应记录告警, count = %d", got)
+	}
+	status = http.StatusOK
+	s.checkBudgets()
+	if got := alerts(); got != 1 {
+		t.Errorf("发送成功后应记录告警, count = %d", got)
+	}
+}
+
+// resetJobRuns 清空任务执行记录
+func resetJobRuns(t *testing.T) {
+	t.Helper()
+	db := model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true})
+	if err := db.Delete(&model.JobRun{}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunJobRecordsResult(t *testing.T) {
+	resetJobRuns(t)
+	t.Cleanup(func() { resetJobRuns(t) })
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+
+	s := New()
+	s.register("ok", "", "0 * * * *", func() error { return nil })
+	s.register("broken", "", "0 * * * *", func() error { return errors.New("boom") })
+
+	if _, err := s.RunJob("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("RunJob(missing) error = %v, want ErrJobNotFound", err)
+	}
+	status, err := s.RunJob("ok")
+	if err != nil || status.Result != JobResultSuccess || status.LastRunAt == nil {
+		t.Errorf("RunJob(ok) = %+v, %v", status, err)
+	}
+	status, err = s.RunJob("broken")
+	if err == nil || status.Result != JobResultFailed || status.Error != "boom" || status.LastSuccessAt != nil {
+		t.Errorf("RunJob(broken) = %+v, %v", status, err)
+	}
+	if jobs := s.Jobs(); len(jobs) != 2 || jobs[0].Name != "ok" {
+		t.Errorf("Jobs() = %+v", jobs)
+	}
+}
+
+func TestCheckAndNotifyRetriesRenewalAfterFailedRun(t *testing.T) {
+	resetSubscriptions(t)
+	resetJobRuns(t)
+	t.Cleanup(func() { resetJobRuns(t) })
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 5, 0, 0, time.UTC))))
+
+	// 09:00 的执行失败，上次成功执行在 08:59；之后的执行仍应续订 9 点到期的订阅
+	success := time.Date(2026, 3, 20, 8, 59, 0, 0, time.UTC)
+	run := model.JobRun{Name: JobCheckNotify, LastRunAt: success.Add(time.Minute), LastSuccessAt: &success, Result: JobResultFailed}
+	if err := model.GetDB().Create(&run).Error; err != nil {
+		t.Fatal(err)
+	}
+	sub := createSubscription(t, "2026-03-20", true, "UTC")
+	if err := New().checkAndNotify(); err != nil {
+		t.Fatal(err)
+	}
+	if got := reload(t, sub.ID); got.RenewCount != 1 {
+		t.Errorf("renew_count = %d, want 1", got.RenewCount)
+	}
+}
+
+func TestCatchUpRenewsOverdueOutsideNotifyHour(t *testing.T) {
+	resetSubscriptions(t)
+	resetJobRuns(t)
+	t.Cleanup(func() { resetJobRuns(t) })
+	// 08:00 不在默认通知时段，启动补做仍应立即续订
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC))))
+
+	sub := createSubscription(t, "2026-03-19", true, "UTC")
+	s := New()
+	s.register(JobCheckNotify, "", "0 * * * *", s.checkAndNotify)
+	s.catchUp()
+
+	if got := reload(t, sub.ID); got.RenewCount != 1 {
+		t.Errorf("renew_count = %d, want 1", got.RenewCount)
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	resetSubscriptions(t)
+	setAutoRenewPolicy(t, model.AutoRenewBackfill)
+	now := time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC)
+	t.Cleanup(clock.Set(clock.NewFixed(now)))
+
+	soon := createSubscription(t, "2026-03-25", false, "UTC")
+	createSubscription(t, "2026-04-30", false, "UTC")
+	snoozed := createSubscription(t, "2026-03-24", false, "UTC")
+	acked := createSubscription(t, "2026-03-23", false, "UTC")
+	if err := model.GetDB().Model(snoozed).Update("snooze_until", date("2026-03-22")).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := model.GetDB().Model(acked).Update("acked_expire", date("2026-03-23")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 自动续订后关闭自动续订的订阅仍列出其自动续订；开启自动续订的订阅的手动续订不列出
+	renewed := createSubscription(t, "2026-03-19", true, "UTC")
+	if _, err := New().autoRenewIfNeeded(renewed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := model.GetDB().Model(renewed).Update("auto_renew", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	manual := createSubscription(t, "2026-05-01", true, "UTC")
+	if err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		_, err := manual.Renew(tx, manual.ExpireDate)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	digest, err := service.BuildDigest(service.DigestWeekly, now, now.Add(-service.DigestLookback(service.DigestWeekly)), 7, "cny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digest.Upcoming) != 1 || digest.Upcoming[0].Subscription.ID != soon.ID {
+		t.Errorf("Upcoming = %+v, want only subscription %d", digest.Upcoming, soon.ID)
+	}
+	if len(digest.Renewals) != 1 || digest.Renewals[0].Renewal.SubscriptionID != renewed.ID {
+		t.Errorf("Renewals = %+v, want one renewal of subscription %d", digest.Renewals, renewed.ID)
+	}
+	if digest.Currency != "CNY" {
+		t.Errorf("Currency = %q, want CNY", digest.Currency)
+	}
+}
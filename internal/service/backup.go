@@ -0,0 +1,495 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+// BackupVersion 当前 JSON 备份格式版本，结构变化时递增
+const BackupVersion = 1
+
+// BackupApp 备份文件标识
+const BackupApp = "subdock"
+
+// 恢复模式
+const (
+	RestoreModeMerge   = "merge"   // 按名称/键合并到现有数据
+	RestoreModeReplace = "replace" // 清空现有数据后按备份原样恢复
+)
+
+// secretSettingKeys 敏感设置项，未要求导出敏感信息时不包含
+var secretSettingKeys = map[string]bool{
+	SettingTelegramBotToken:             true,
+	SettingBarkURL:                      true,
+	model.SettingOverdueEscalateBarkURL: true,
+}
+
+// BackupUser 备份中的用户，未要求导出敏感信息时不包含密码哈希
+type BackupUser struct {
+	ID            uint      `json:"id"`
+	Username      string    `json:"username"`
+	PasswordHash  string    `json:"password_hash,omitempty"`
+	CalendarToken string    `json:"calendar_token,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// BackupSubscriptionTag 订阅与标签的关联
+type BackupSubscriptionTag struct {
+	SubscriptionID uint `json:"subscription_id"`
+	TagID          uint `json:"tag_id"`
+}
+
+// Backup 整个实例的 JSON 备份
+type Backup struct {
+	App              string                      `json:"app"`
+	Version          int                         `json:"version"`
+	ExportedAt       time.Time                   `json:"exported_at"`
+	IncludesSecrets  bool                        `json:"includes_secrets"`
+	Users            []BackupUser                `json:"users"`
+	Settings         []model.Setting             `json:"settings"`
+	Categories       []model.Category            `json:"categories"`
+	Tags             []model.Tag                 `json:"tags"`
+	Subscriptions    []model.Subscription        `json:"subscriptions"`
+	SubscriptionTags []BackupSubscriptionTag     `json:"subscription_tags"`
+	Renewals         []model.SubscriptionRenewal `json:"renewals"`
+	Budgets          []model.Budget              `json:"budgets"`
+	BudgetAlerts     []model.BudgetAlert         `json:"budget_alerts"`
+	ExchangeRates    []model.ExchangeRate        `json:"exchange_rates"`
+}
+
+// RestoreResult 恢复结果，按实体统计写入数量
+type RestoreResult struct {
+	Mode    string         `json:"mode"`
+	Counts  map[string]int `json:"counts"`
+	Skipped []string       `json:"skipped"`
+}
+
+// ExportBackup 导出全部数据，includeSecrets 为 true 时包含密码哈希与通知密钥
+func ExportBackup(includeSecrets bool) (*Backup, error) {
+	db := model.GetDB()
+	backup := &Backup{
+		App:             BackupApp,
+		Version:         BackupVersion,
+		ExportedAt:      clock.Now(),
+		IncludesSecrets: includeSecrets,
+	}
+
+	var admins []model.Admin
+	if err := db.Order("id asc").Find(&admins).Error; err != nil {
+		return nil, err
+	}
+	backup.Users = make([]BackupUser, 0, len(admins))
+	for _, admin := range admins {
+		user := BackupUser{ID: admin.ID, Username: admin.Username, Timezone: admin.Timezone, CreatedAt: admin.CreatedAt, UpdatedAt: admin.UpdatedAt}
+		if includeSecrets {
+			user.PasswordHash = admin.PasswordHash
+			user.CalendarToken = admin.CalendarToken
+		}
+		backup.Users = append(backup.Users, user)
+	}
+
+	var settings []model.Setting
+	if err := db.Order("key asc").Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	backup.Settings = make([]model.Setting, 0, len(settings))
+	for _, s := range settings {
+		if secretSettingKeys[s.Key] && !includeSecrets {
+			continue
+		}
+		backup.Settings = append(backup.Settings, s)
+	}
+
+	if err := db.Order("id asc").Find(&backup.Categories).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id asc").Find(&backup.Tags).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id asc").Find(&backup.Subscriptions).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Table("subscription_tags").
+		Select("subscription_tags.subscription_id, subscription_tags.tag_id").
+		Joins("JOIN subscriptions ON subscriptions.id = subscription_tags.subscription_id AND subscriptions.deleted_at IS NULL").
+		Order("subscription_tags.subscription_id asc, subscription_tags.tag_id asc").
+		Scan(&backup.SubscriptionTags).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("subscription_id IN (?)", db.Model(&model.Subscription{}).Select("id")).
+		Order("id asc").Find(&backup.Renewals).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id asc").Find(&backup.Budgets).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id asc").Find(&backup.BudgetAlerts).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("id asc").Find(&backup.ExchangeRates).Error; err != nil {
+		return nil, err
+	}
+
+	// 备份中的标签关联单独存放，订阅本身不带关联数据
+	for i := range backup.Subscriptions {
+		backup.Subscriptions[i].Tags = nil
+	}
+	return backup, nil
+}
+
+// ValidateBackup 校验备份标识与版本
+func ValidateBackup(b *Backup) error {
+	if b.App != BackupApp {
+		return errors.New("不是 SubDock 备份文件")
+	}
+	if b.Version <= 0 || b.Version > BackupVersion {
+		return fmt.Errorf("不支持的备份版本: %d（当前支持 %d）", b.Version, BackupVersion)
+	}
+	for _, sub := range b.Subscriptions {
+		if sub.ID == 0 || sub.Name == "" {
+			return errors.New("备份中的订阅缺少 ID 或名称")
+		}
+	}
+	return nil
+}
+
+// RestoreBackup 在一个事务中恢复备份
+func RestoreBackup(b *Backup, mode string) (*RestoreResult, error) {
+	if err := ValidateBackup(b); err != nil {
+		return nil, err
+	}
+	if mode != RestoreModeMerge && mode != RestoreModeReplace {
+		return nil, fmt.Errorf("不支持的恢复模式: %s", mode)
+	}
+
+	result := &RestoreResult{Mode: mode, Counts: map[string]int{}, Skipped: []string{}}
+	err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		if mode == RestoreModeReplace {
+			return restoreReplace(tx, b, result)
+		}
+		return restoreMerge(tx, b, result)
+	})
+	model.InvalidateSettings()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// restoreReplace 清空业务数据后按原 ID 写入备份内容
+// 备份不含密码哈希时保留现有用户；备份中缺失的敏感设置保留现有值
+func restoreReplace(tx *gorm.DB, b *Backup, result *RestoreResult) error {
+	for _, stmt := range []string{
+		"DELETE FROM subscription_tags",
+		"DELETE FROM subscription_renewals",
+		"DELETE FROM budget_alerts",
+		"DELETE FROM budgets",
+		"DELETE FROM subscriptions",
+		"DELETE FROM tags",
+		"DELETE FROM categories",
+		"DELETE FROM exchange_rates",
+	} {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	backupKeys := make(map[string]bool)
+	for _, s := range b.Settings {
+		backupKeys[s.Key] = true
+	}
+	var existing []model.Setting
+	if err := tx.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, s := range existing {
+		if secretSettingKeys[s.Key] && !backupKeys[s.Key] {
+			continue
+		}
+		if err := tx.Delete(&s).Error; err != nil {
+			return err
+		}
+	}
+	for _, s := range b.Settings {
+		if err := tx.Create(&model.Setting{Key: s.Key, Value: s.Value}).Error; err != nil {
+			return err
+		}
+	}
+	result.Counts["settings"] = len(b.Settings)
+
+	if err := restoreUsersReplace(tx, b, result); err != nil {
+		return err
+	}
+
+	if err := createAll(tx, b.Categories); err != nil {
+		return err
+	}
+	result.Counts["categories"] = len(b.Categories)
+	if err := createAll(tx, b.Tags); err != nil {
+		return err
+	}
+	result.Counts["tags"] = len(b.Tags)
+
+	for i := range b.Subscriptions {
+		b.Subscriptions[i].Tags = nil
+		b.Subscriptions[i].Category = nil
+	}
+	if err := createAll(tx, b.Subscriptions); err != nil {
+		return err
+	}
+	result.Counts["subscriptions"] = len(b.Subscriptions)
+
+	for _, st := range b.SubscriptionTags {
+		if err := tx.Exec("INSERT INTO subscription_tags (subscription_id, tag_id) VALUES (?, ?)", st.SubscriptionID, st.TagID).Error; err != nil {
+			return err
+		}
+	}
+	result.Counts["subscription_tags"] = len(b.SubscriptionTags)
+
+	if err := createAll(tx, b.Renewals); err != nil {
+		return err
+	}
+	result.Counts["renewals"] = len(b.Renewals)
+	if err := createAll(tx, b.Budgets); err != nil {
+		return err
+	}
+	result.Counts["budgets"] = len(b.Budgets)
+	if err := createAll(tx, b.BudgetAlerts); err != nil {
+		return err
+	}
+	result.Counts["budget_alerts"] = len(b.BudgetAlerts)
+	if err := createAll(tx, b.ExchangeRates); err != nil {
+		return err
+	}
+	result.Counts["exchange_rates"] = len(b.ExchangeRates)
+	return nil
+}
+
+// restoreUsersReplace 备份包含密码哈希时替换全部用户，否则保留现有用户
+func restoreUsersReplace(tx *gorm.DB, b *Backup, result *RestoreResult) error {
+	var users []model.Admin
+	for _, u := range b.Users {
+		if u.PasswordHash == "" {
+			continue
+		}
+		users = append(users, model.Admin{
+			ID:            u.ID,
+			Username:      u.Username,
+			PasswordHash:  u.PasswordHash,
+			CalendarToken: u.CalendarToken,
+			Timezone:      u.Timezone,
+			CreatedAt:     u.CreatedAt,
+			UpdatedAt:     u.UpdatedAt,
+		})
+	}
+	if len(users) == 0 {
+		if len(b.Users) > 0 {
+			result.Skipped = append(result.Skipped, "users: 备份不含密码哈希，保留现有用户")
+		}
+		return nil
+	}
+
+	if err := tx.Exec("DELETE FROM admins").Error; err != nil {
+		return err
+	}
+	if err := createAll(tx, users); err != nil {
+		return err
+	}
+	result.Counts["users"] = len(users)
+	return nil
+}
+
+// restoreMerge 合并恢复：分类、标签、预算、订阅按名称匹配，设置按键覆盖，汇率按日期去重
+// 新建记录使用新 ID，关联关系按映射后的 ID 重建
+func restoreMerge(tx *gorm.DB, b *Backup, result *RestoreResult) error {
+	for _, s := range b.Settings {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}).Create(&model.Setting{Key: s.Key, Value: s.Value}).Error; err != nil {
+			return err
+		}
+	}
+	result.Counts["settings"] = len(b.Settings)
+
+	for _, u := range b.Users {
+		if u.PasswordHash == "" {
+			result.Skipped = append(result.Skipped, "users: "+u.Username+" 不含密码哈希")
+			continue
+		}
+		var admin model.Admin
+		if err := tx.Where(model.Admin{Username: u.Username}).
+			Assign(model.Admin{PasswordHash: u.PasswordHash}).
+			FirstOrCreate(&admin).Error; err != nil {
+			return err
+		}
+		result.Counts["users"]++
+	}
+
+	categoryIDs := make(map[uint]uint)
+	for _, c := range b.Categories {
+		var category model.Category
+		if err := tx.Where(model.Category{Name: c.Name}).
+			Assign(model.Category{Color: c.Color, Icon: c.Icon}).
+			FirstOrCreate(&category).Error; err != nil {
+			return err
+		}
+		categoryIDs[c.ID] = category.ID
+		result.Counts["categories"]++
+	}
+
+	tagIDs := make(map[uint]uint)
+	for _, t := range b.Tags {
+		var tag model.Tag
+		if err := tx.Where(model.Tag{Name: t.Name}).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		tagIDs[t.ID] = tag.ID
+		result.Counts["tags"]++
+	}
+
+	subscriptionIDs := make(map[uint]uint)
+	for _, s := range b.Subscriptions {
+		var categoryID *uint
+		if s.CategoryID != nil {
+			if id, ok := categoryIDs[*s.CategoryID]; ok {
+				categoryID = &id
+			}
+		}
+
+		var existing model.Subscription
+		found := tx.Where("name = ?", s.Name).Order("id asc").Limit(1).Find(&existing)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected > 0 {
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"amount":           s.Amount,
+				"currency":         s.Currency,
+				"start_date":       s.StartDate,
+				"billing_type":     s.BillingType,
+				"cycle_value":      s.CycleValue,
+				"cycle_unit":       s.CycleUnit,
+				"cycle_rule":       s.CycleRule,
+				"expire_date":      s.ExpireDate,
+				"support_end_date": s.SupportEndDate,
+				"auto_renew":       s.AutoRenew,
+				"renew_count":      s.RenewCount,
+				"anchor_day":       s.AnchorDay,
+				"remind_offsets":   s.RemindOffsets,
+				"timezone":         s.Timezone,
+				"status":           s.Status,
+				"category_id":      categoryID,
+				"remark":           s.Remark,
+			}).Error; err != nil {
+				return err
+			}
+			subscriptionIDs[s.ID] = existing.ID
+			result.Counts["subscriptions_updated"]++
+			continue
+		}
+
+		sub := s
+		sub.ID = 0
+		sub.CategoryID = categoryID
+		sub.Category = nil
+		sub.Tags = nil
+		if err := tx.Omit(clause.Associations).Create(&sub).Error; err != nil {
+			return err
+		}
+		subscriptionIDs[s.ID] = sub.ID
+		result.Counts["subscriptions_created"]++
+	}
+
+	for _, st := range b.SubscriptionTags {
+		subID, ok1 := subscriptionIDs[st.SubscriptionID]
+		tagID, ok2 := tagIDs[st.TagID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if err := tx.Exec("INSERT OR IGNORE INTO subscription_tags (subscription_id, tag_id) VALUES (?, ?)", subID, tagID).Error; err != nil {
+			return err
+		}
+		result.Counts["subscription_tags"]++
+	}
+
+	for _, r := range b.Renewals {
+		subID, ok := subscriptionIDs[r.SubscriptionID]
+		if !ok {
+			continue
+		}
+		var count int64
+		if err := tx.Model(&model.SubscriptionRenewal{}).
+			Where("subscription_id = ? AND renew_count = ? AND new_expire_date = ?", subID, r.RenewCount, r.NewExpireDate).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		renewal := r
+		renewal.ID = 0
+		renewal.SubscriptionID = subID
+		if err := tx.Create(&renewal).Error; err != nil {
+			return err
+		}
+		result.Counts["renewals"]++
+	}
+
+	for _, bg := range b.Budgets {
+		var categoryID *uint
+		if bg.CategoryID != nil {
+			id, ok := categoryIDs[*bg.CategoryID]
+			if !ok {
+				result.Skipped = append(result.Skipped, "budgets: "+bg.Name+" 的分类不存在")
+				continue
+			}
+			categoryID = &id
+		}
+
+		var budget model.Budget
+		if err := tx.Where(model.Budget{Name: bg.Name}).
+			Assign(map[string]interface{}{
+				"period":      bg.Period,
+				"category_id": categoryID,
+				"amount":      bg.Amount,
+				"currency":    bg.Currency,
+				"thresholds":  bg.Thresholds,
+				"enabled":     bg.Enabled,
+			}).
+			FirstOrCreate(&budget).Error; err != nil {
+			return err
+		}
+		result.Counts["budgets"]++
+	}
+
+	for _, rate := range b.ExchangeRates {
+		r := rate
+		r.ID = 0
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "base"}, {Name: "currency"}, {Name: "rate_date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "fetched_at"}),
+		}).Create(&r).Error; err != nil {
+			return err
+		}
+		result.Counts["exchange_rates"]++
+	}
+	return nil
+}
+
+// createAll 批量写入记录（保留原 ID，不处理关联）
+func createAll[T any](tx *gorm.DB, records []T) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).CreateInBatches(records, 200).Error
+}
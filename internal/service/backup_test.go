@@ -0,0 +1,52 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"subdock/internal/model"
+)
+
+func TestBackupRoundTripRemindOffsets(t *testing.T) {
+	resetSubscriptions(t)
+	sub := createSubscription(t, "Netflix", 10, "2026-03-25")
+	if err := model.GetDB().Model(sub).Updates(map[string]interface{}{
+		"remind_offsets": "30,7,0,-3",
+		"timezone":       "Asia/Shanghai",
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ExportBackup(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Version != 1 || !strings.Contains(string(data), `"remind_offsets":"30,7,0,-3"`) || strings.Contains(string(data), "remind_days") {
+		t.Fatalf("备份版本 %d，应以 remind_offsets 导出提醒偏移: %s", backup.Version, data)
+	}
+
+	if err := model.GetDB().Model(sub).Updates(map[string]interface{}{"remind_offsets": "", "timezone": ""}).Error; err != nil {
+		t.Fatal(err)
+	}
+	var restored Backup
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestoreBackup(&restored, RestoreModeMerge); err != nil {
+		t.Fatal(err)
+	}
+	got := reload(t, sub.ID)
+	if got.RemindOffsets != "30,7,0,-3" || got.Timezone != "Asia/Shanghai" || got.AnchorDay != 25 {
+		t.Errorf("restored remind_offsets = %q, timezone = %q, anchor_day = %d", got.RemindOffsets, got.Timezone, got.AnchorDay)
+	}
+
+	restored.Version = 2
+	if err := ValidateBackup(&restored); err == nil {
+		t.Error("不支持的备份版本应返回错误")
+	}
+}
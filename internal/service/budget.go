@@ -0,0 +1,253 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/model"
+)
+
+// 预算告警类型
+const (
+	BudgetAlertActual    = "actual"    // 实际支出达到阈值
+	BudgetAlertProjected = "projected" // 预计本周期支出达到阈值
+)
+
+// BudgetStatus 预算执行情况
+type BudgetStatus struct {
+	Budget           model.Budget `json:"budget"`
+	PeriodKey        string       `json:"period_key"`
+	PeriodStart      time.Time    `json:"period_start"`
+	PeriodEnd        time.Time    `json:"period_end"`
+	Spent            float64      `json:"spent"`     // 本周期已发生支出
+	Projected        float64      `json:"projected"` // 已发生支出 + 本周期剩余预计扣费
+	Percent          float64      `json:"percent"`
+	ProjectedPercent float64      `json:"projected_percent"`
+	Unconverted      []string     `json:"unconverted"` // 缺少汇率未计入的币种
+}
+
+// ParseThresholds 解析逗号分隔的百分比阈值，返回升序去重后的结果
+func ParseThresholds(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	var thresholds []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil || v <= 0 || v > 1000 {
+			return nil, fmt.Errorf("无效的阈值: %s", p)
+		}
+		if !seen[v] {
+			seen[v] = true
+			thresholds = append(thresholds, v)
+		}
+	}
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("至少需要一个阈值")
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+// BudgetPeriodRange 返回 now 所在预算周期的起止时间 [start, end) 及周期标识，周期按 now 所属时区的日历日期划分
+func BudgetPeriodRange(period model.BudgetPeriod, now time.Time) (time.Time, time.Time, string) {
+	now = model.CalendarDate(now)
+	if period == model.BudgetPeriodYear {
+		start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0), start.Format("2006")
+	}
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0), start.Format("2006-01")
+}
+
+// EvaluateBudget 计算预算在 now 所在周期内的执行情况
+// 实际支出：周期内新开始的订阅首期费用 + 周期内的续订记录，按支付当日汇率换算
+// 预计支出：实际支出 + 从今天到周期结束的预计扣费
+func EvaluateBudget(budget model.Budget, now time.Time) (*BudgetStatus, error) {
+	start, end, key := BudgetPeriodRange(budget.Period, now)
+	status := &BudgetStatus{
+		Budget:      budget,
+		PeriodKey:   key,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Unconverted: []string{},
+	}
+
+	addSpend := func(amount float64, currency string, at time.Time, projected bool) {
+		converted, err := ConvertAmount(amount, currency, budget.Currency, at)
+		if err != nil {
+			if !containsString(status.Unconverted, currency) {
+				status.Unconverted = append(status.Unconverted, currency)
+			}
+			return
+		}
+		if !projected {
+			status.Spent += converted
+		}
+		status.Projected += converted
+	}
+
+	// 分类预算只统计该分类下的订阅
+	scope := func(db *gorm.DB) *gorm.DB {
+		if budget.CategoryID == nil {
+			return db
+		}
+		return db.Where("category_id = ?", *budget.CategoryID)
+	}
+
+	var started []model.Subscription
+	if err := model.GetDB().Scopes(scope).Where("start_date >= ? AND start_date < ? AND start_date <= ?", start, end, now).
+		Find(&started).Error; err != nil {
+		return nil, err
+	}
+	for _, sub := range started {
+		addSpend(sub.Amount, sub.Currency, sub.StartDate, false)
+	}
+
+	renewalQuery := model.GetDB().Where("renewed_at >= ? AND renewed_at < ?", start, end)
+	if budget.CategoryID != nil {
+		renewalQuery = renewalQuery.Where("subscription_id IN (?)",
+			model.GetDB().Unscoped().Model(&model.Subscription{}).Select("id").Where("category_id = ?", *budget.CategoryID))
+	}
+	var renewals []model.SubscriptionRenewal
+	if err := renewalQuery.Find(&renewals).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range renewals {
+		addSpend(r.Amount, r.Currency, r.RenewedAt, false)
+	}
+
+	var active []model.Subscription
+	if err := model.GetDB().Scopes(scope).Where("status = ?", model.StatusActive).Find(&active).Error; err != nil {
+		return nil, err
+	}
+	from := model.CalendarDate(now)
+	for i := range active {
+		// 一次性购买的扣费在开始日期，已开始的已计入实际支出
+		if !active[i].IsRecurring() && !active[i].StartDate.After(now) {
+			continue
+		}
+		for _, date := range ProjectCharges(&active[i], from, end) {
+			addSpend(active[i].Amount, active[i].Currency, date, true)
+		}
+	}
+
+	status.Spent = roundMoney(status.Spent)
+	status.Projected = roundMoney(status.Projected)
+	if budget.Amount > 0 {
+		status.Percent = roundMoney(status.Spent / budget.Amount * 100)
+		status.ProjectedPercent = roundMoney(status.Projected / budget.Amount * 100)
+	}
+	return status, nil
+}
+
+// BudgetStatuses 计算所有启用预算的执行情况
+func BudgetStatuses(now time.Time) ([]BudgetStatus, error) {
+	var budgets []model.Budget
+	if err := model.GetDB().Where("enabled = ?", true).Order("id asc").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+
+	statuses := []BudgetStatus{}
+	for _, budget := range budgets {
+		status, err := EvaluateBudget(budget, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// PendingBudgetAlerts 返回本周期内新越过、尚未告警的阈值，不记录告警；发送成功后调用 RecordBudgetAlert 记录
+// 同一阈值同时越过实际和预计时只发送实际告警
+func PendingBudgetAlerts(status *BudgetStatus, now time.Time) ([]model.BudgetAlert, error) {
+	thresholds, err := ParseThresholds(status.Budget.Thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []model.BudgetAlert
+	for _, threshold := range thresholds {
+		kind, spent := "", 0.0
+		switch {
+		case status.Percent >= float64(threshold):
+			kind, spent = BudgetAlertActual, status.Spent
+		case status.ProjectedPercent >= float64(threshold):
+			kind, spent = BudgetAlertProjected, status.Projected
+		default:
+			continue
+		}
+
+		var count int64
+		if err := model.GetDB().Model(&model.BudgetAlert{}).
+			Where("budget_id = ? AND period_key = ? AND threshold = ? AND kind = ?", status.Budget.ID, status.PeriodKey, threshold, kind).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		alert := model.BudgetAlert{
+			BudgetID:  status.Budget.ID,
+			PeriodKey: status.PeriodKey,
+			Threshold: threshold,
+			Kind:      kind,
+			Spent:     spent,
+			AlertedAt: now,
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// RecordBudgetAlert 记录已发送的预算告警，同一周期内不再重复发送；已记录时忽略
+func RecordBudgetAlert(alert *model.BudgetAlert) error {
+	return model.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(alert).Error
+}
+
+// FormatBudgetAlert 格式化预算告警消息
+func FormatBudgetAlert(status *BudgetStatus, alert model.BudgetAlert) string {
+	label := "已达到"
+	percent := status.Percent
+	if alert.Kind == BudgetAlertProjected {
+		label = "预计将达到"
+		percent = status.ProjectedPercent
+	}
+	return fmt.Sprintf("💰 预算提醒\n\n预算: %s（%s%s）\n周期: %s\n%s %d%% 阈值\n预算金额: %.2f %s\n已支出: %.2f %s\n预计支出: %.2f %s\n当前比例: %.2f%%",
+		status.Budget.Name, budgetScopeLabel(status.Budget), budgetPeriodLabel(status.Budget.Period), status.PeriodKey,
+		label, alert.Threshold,
+		status.Budget.Amount, status.Budget.Currency,
+		status.Spent, status.Budget.Currency,
+		status.Projected, status.Budget.Currency,
+		percent)
+}
+
+// budgetScopeLabel 预算范围显示名称
+func budgetScopeLabel(budget model.Budget) string {
+	if budget.CategoryID == nil {
+		return "总 "
+	}
+	var category model.Category
+	if err := model.GetDB().First(&category, *budget.CategoryID).Error; err != nil {
+		return "分类"
+	}
+	return category.Name + " "
+}
+
+// budgetPeriodLabel 预算周期显示名称
+func budgetPeriodLabel(period model.BudgetPeriod) string {
+	if period == model.BudgetPeriodYear {
+		return "年度"
+	}
+	return "月度"
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"80,100", "[80 100]", false},
+		{" 100, 50 ,80,100 ", "[50 80 100]", false},
+		{"120", "[120]", false},
+		{"", "", true},
+		{"0", "", true},
+		{"abc", "", true},
+		{"1001", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseThresholds(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseThresholds(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && fmt.Sprint(got) != tt.want {
+			t.Errorf("ParseThresholds(%q) = %v, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPendingBudgetAlertsThresholdCrossing(t *testing.T) {
+	const budgetID = 9001
+	t.Cleanup(func() { model.GetDB().Where("budget_id = ?", budgetID).Delete(&model.BudgetAlert{}) })
+	now := time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		spent     float64
+		projected float64
+		recorded  []string // 本周期已记录的告警，格式为 kind:threshold
+		want      []string
+	}{
+		{"均未达到阈值", 50, 79.99, nil, nil},
+		{"预计支出越过 80%", 50, 80, nil, []string{"projected:80"}},
+		{"实际越过 80% 且预计越过 100%", 85, 120, nil, []string{"actual:80", "projected:100"}},
+		{"实际越过时不再发送同一阈值的预计告警", 100, 100, nil, []string{"actual:80", "actual:100"}},
+		{"已告警的阈值不重复", 85, 120, []string{"actual:80", "projected:100"}, nil},
+		{"预计告警之后实际越过仍告警", 85, 90, []string{"projected:80"}, []string{"actual:80"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := model.GetDB().Where("budget_id = ?", budgetID).Delete(&model.BudgetAlert{}).Error; err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range tt.recorded {
+				kind, threshold, _ := strings.Cut(r, ":")
+				n, _ := strconv.Atoi(threshold)
+				if err := RecordBudgetAlert(&model.BudgetAlert{BudgetID: budgetID, PeriodKey: "2026-03", Threshold: n, Kind: kind, AlertedAt: now}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status := &BudgetStatus{
+				Budget:           model.Budget{ID: budgetID, Amount: 100, Thresholds: "100,80"},
+				PeriodKey:        "2026-03",
+				Spent:            tt.spent,
+				Projected:        tt.projected,
+				Percent:          tt.spent,
+				ProjectedPercent: tt.projected,
+			}
+			alerts, err := PendingBudgetAlerts(status, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range alerts {
+				got = append(got, fmt.Sprintf("%s:%d", a.Kind, a.Threshold))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("alerts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateBudget(t *testing.T) {
+	resetSubscriptions(t)
+	resetRates(t)
+	now := time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC)
+	t.Cleanup(clock.Set(clock.NewFixed(now)))
+
+	category := model.Category{Name: "影音"}
+	if err := model.GetDB().Create(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 本月开始的订阅计入首期费用，之后的续订按预计扣费计入
+	started := createSubscription(t, "Netflix", 30, "2026-04-05")
+	if err := model.GetDB().Model(started).Updates(map[string]interface{}{"auto_renew": true, "category_id": category.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 本月的续订记录计入实际支出，本月剩余的到期按预计扣费计入
+	renewed := createSubscription(t, "iCloud", 20, "2026-03-25")
+	renewal := model.SubscriptionRenewal{SubscriptionID: renewed.ID, RenewedAt: date("2026-03-10"), Amount: 20, Currency: "CNY"}
+	if err := model.GetDB().Create(&renewal).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		budget    model.Budget
+		spent     float64
+		projected float64
+		percent   float64
+	}{
+		{"月度总预算", model.Budget{Period: model.BudgetPeriodMonth, Amount: 100, Currency: "CNY"}, 50, 70, 50},
+		{"月度分类预算", model.Budget{Period: model.BudgetPeriodMonth, Amount: 60, Currency: "CNY", CategoryID: &category.ID}, 30, 30, 50},
+		// iCloud 于 2 月开始，首期费用计入年度实际支出；Netflix 4-12 月预计续订 9 次
+		{"年度总预算包含之后的续订", model.Budget{Period: model.BudgetPeriodYear, Amount: 1000, Currency: "CNY"}, 70, 70 + 20 + 30*9, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := EvaluateBudget(tt.budget, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Spent != tt.spent || status.Projected != tt.projected || status.Percent != tt.percent {
+				t.Errorf("spent/projected/percent = %v/%v/%v, want %v/%v/%v",
+					status.Spent, status.Projected, status.Percent, tt.spent, tt.projected, tt.percent)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"subdock/internal/model"
+)
+
+// 日历事件类型
+const (
+	CalendarEventExpire  = "expire"  // 到期（未开启自动续订）
+	CalendarEventExpired = "expired" // 已过期且未续订
+	CalendarEventRenew   = "renew"   // 续订（开启自动续订，含预计的后续续订）
+
+	CalendarEventSupportEnd = "support_end" // 支持/更新截止
+)
+
+// CalendarOptions 日历生成选项
+type CalendarOptions struct {
+	Name   string    // 日历名称
+	From   time.Time // 今天，已过期的自动续订订阅从这一天起推算后续续订
+	Until  time.Time // 预计续订的截止日期（不含）
+	Domain string    // UID 域名部分
+}
+
+// CalendarEvent 日历中的一个全天事件
+type CalendarEvent struct {
+	UID          string
+	Kind         string
+	Date         time.Time
+	Subscription *model.Subscription
+}
+
+// CalendarEvents 生成订阅的日历事件：当前到期日、支持/更新截止日，以及自动续订订阅在 until 之前的后续续订
+// UID 由订阅 ID 和日期组成，重新生成时保持不变
+func CalendarEvents(subscriptions []model.Subscription, from, until time.Time, domain string) []CalendarEvent {
+	var events []CalendarEvent
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if sub.Status != "" && sub.Status != model.StatusActive {
+			continue
+		}
+
+		subEvents := subscriptionCalendarEvents(sub, from, until, domain)
+		// 支持截止日与到期或续订日相同时只保留到期或续订事件
+		if sub.SupportEndDate != nil {
+			support := model.CalendarDate(*sub.SupportEndDate)
+			duplicate := false
+			for _, e := range subEvents {
+				duplicate = duplicate || e.Date.Equal(support)
+			}
+			if !duplicate {
+				subEvents = append(subEvents, newCalendarEvent(sub, CalendarEventSupportEnd, support, domain))
+			}
+		}
+		events = append(events, subEvents...)
+	}
+	return events
+}
+
+// subscriptionCalendarEvents 生成订阅的到期或续订事件，自动续订订阅包含 until 之前的后续续订
+func subscriptionCalendarEvents(sub *model.Subscription, from, until time.Time, domain string) []CalendarEvent {
+	if !sub.HasExpiry() {
+		return nil
+	}
+
+	kind := CalendarEventExpire
+	if sub.AutoRenew && sub.IsRecurring() {
+		kind = CalendarEventRenew
+	}
+
+	date := model.CalendarDate(sub.ExpireDate)
+	if kind == CalendarEventExpire && date.Before(from) {
+		kind = CalendarEventExpired
+	}
+	events := []CalendarEvent{newCalendarEvent(sub, kind, date, domain)}
+	if kind != CalendarEventRenew {
+		return events
+	}
+
+	cycle := *sub
+	if cycle.CycleValue <= 0 {
+		cycle.CycleValue = 1
+	}
+	// 已过期的自动续订订阅会在今天续订，后续续订从今天起推算
+	if date.Before(from) {
+		date = from
+	}
+	for next, ok := cycle.NextCycleDate(date); ok && next.Before(until); next, ok = cycle.NextCycleDate(next) {
+		events = append(events, newCalendarEvent(sub, CalendarEventRenew, next, domain))
+	}
+	return events
+}
+
+// newCalendarEvent 创建日历事件
+func newCalendarEvent(sub *model.Subscription, kind string, date time.Time, domain string) CalendarEvent {
+	return CalendarEvent{
+		UID:          fmt.Sprintf("subdock-%d-%s@%s", sub.ID, date.Format("20060102"), domain),
+		Kind:         kind,
+		Date:         date,
+		Subscription: sub,
+	}
+}
+
+// BuildCalendar 生成 iCalendar 文本
+func BuildCalendar(subscriptions []model.Subscription, opts CalendarOptions) string {
+	if opts.Domain == "" {
+		opts.Domain = "subdock"
+	}
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//SubDock//Subscription Calendar//ZH")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText(opts.Name))
+
+	for _, event := range CalendarEvents(subscriptions, opts.From, opts.Until, opts.Domain) {
+		sub := event.Subscription
+		summary := "⏰ " + sub.Name + " 到期"
+		switch event.Kind {
+		case CalendarEventRenew:
+			summary = "🔄 " + sub.Name + " 续订"
+		case CalendarEventSupportEnd:
+			summary = "🛠 " + sub.Name + " 支持到期"
+		case CalendarEventExpired:
+			summary = "⚠️ " + sub.Name + " 已过期"
+		}
+		description := fmt.Sprintf("金额: %.2f %s\n周期: %d %s", sub.Amount, sub.Currency, sub.CycleValue, sub.CycleUnit)
+		if !sub.IsRecurring() {
+			description = fmt.Sprintf("金额: %.2f %s\n类型: %s", sub.Amount, sub.Currency, sub.BillingType)
+		}
+		if sub.Remark != "" {
+			description += "\n备注: " + sub.Remark
+		}
+
+		// DTSTAMP 使用订阅更新时间，内容不变时重新生成的日历完全一致
+		stamp := sub.UpdatedAt
+		if stamp.IsZero() {
+			stamp = event.Date
+		}
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+event.UID)
+		writeICSLine(&b, "DTSTAMP:"+stamp.UTC().Format("20060102T150405Z"))
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+event.Date.Format("20060102"))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(summary))
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(description))
+		if sub.Category != nil {
+			writeICSLine(&b, "CATEGORIES:"+escapeICSText(sub.Category.Name))
+		}
+		writeICSLine(&b, "TRANSP:TRANSPARENT")
+		for _, offset := range sub.EffectiveRemindOffsets() {
+			writeICSLine(&b, "BEGIN:VALARM")
+			writeICSLine(&b, "ACTION:DISPLAY")
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(summary))
+			writeICSLine(&b, "TRIGGER:"+alarmTrigger(offset))
+			writeICSLine(&b, "END:VALARM")
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// alarmTrigger 根据提醒偏移生成 VALARM 触发时间：正数为事件前 N 天，负数为事件后 N 天
+func alarmTrigger(offset int) string {
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("-P%dD", offset)
+	case offset < 0:
+		return fmt.Sprintf("P%dD", -offset)
+	default:
+		return "PT0S"
+	}
+}
+
+// escapeICSText 转义 iCalendar 文本值
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeICSLine 写入一行，超过 75 字节时按 RFC 5545 折行，不拆分多字节字符
+func writeICSLine(b *strings.Builder, line string) {
+	const limit = 75
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"subdock/internal/model"
+)
+
+// calendarSubscription 构造日历测试用的订阅，不写入数据库
+func calendarSubscription(id uint, name, expire string, autoRenew bool) model.Subscription {
+	expireDate := date(expire)
+	return model.Subscription{
+		ID:            id,
+		Name:          name,
+		Amount:        10,
+		Currency:      "CNY",
+		StartDate:     expireDate.AddDate(0, -1, 0),
+		BillingType:   model.BillingRecurring,
+		CycleValue:    1,
+		CycleUnit:     model.CycleUnitMonth,
+		AnchorDay:     expireDate.Day(),
+		ExpireDate:    expireDate,
+		AutoRenew:     autoRenew,
+		RemindOffsets: "0",
+		Status:        model.StatusActive,
+	}
+}
+
+// eventDates 按 UID 汇总事件，返回 UID -> 类型与日期
+func eventDates(events []CalendarEvent) map[string]string {
+	got := make(map[string]string, len(events))
+	for _, e := range events {
+		got[e.UID] = e.Kind + " " + e.Date.Format("2006-01-02")
+	}
+	return got
+}
+
+func TestCalendarEvents(t *testing.T) {
+	from, until := date("2026-03-20"), date("2026-06-25")
+	support := date("2026-05-25")
+	otherSupport := date("2026-04-01")
+	paused := calendarSubscription(5, "Paused", "2026-03-25", false)
+	paused.Status = model.StatusPaused
+
+	subs := []model.Subscription{
+		calendarSubscription(1, "Renew", "2026-03-25", true),
+		calendarSubscription(2, "Expire", "2026-04-10", false),
+		calendarSubscription(3, "Expired", "2026-03-10", false),
+		calendarSubscription(4, "Overdue", "2026-03-01", true),
+		paused,
+	}
+	// 支持截止日与预计续订日相同时只保留续订事件
+	subs[0].SupportEndDate = &support
+	subs[1].SupportEndDate = &otherSupport
+
+	events := CalendarEvents(subs, from, until, "example.com")
+	got := eventDates(events)
+	if len(got) != len(events) {
+		t.Errorf("事件 UID 重复: %d 个事件，%d 个 UID", len(events), len(got))
+	}
+	want := map[string]string{
+		"subdock-1-20260325@example.com": "renew 2026-03-25",
+		"subdock-1-20260425@example.com": "renew 2026-04-25",
+		"subdock-1-20260525@example.com": "renew 2026-05-25",
+		"subdock-2-20260410@example.com": "expire 2026-04-10",
+		"subdock-2-20260401@example.com": "support_end 2026-04-01",
+		"subdock-3-20260310@example.com": "expired 2026-03-10",
+		// 已过期的自动续订订阅从今天起推算后续续订，6 月 20 日之后的续订超出 until
+		"subdock-4-20260301@example.com": "renew 2026-03-01",
+		"subdock-4-20260420@example.com": "renew 2026-04-20",
+		"subdock-4-20260520@example.com": "renew 2026-05-20",
+		"subdock-4-20260620@example.com": "renew 2026-06-20",
+	}
+	if len(got) != len(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	for uid, event := range want {
+		if got[uid] != event {
+			t.Errorf("%s = %q, want %q", uid, got[uid], event)
+		}
+	}
+
+	// 预计续订在 until 之前停止，until 当天不包含
+	events = CalendarEvents(subs[3:4], from, date("2026-04-20"), "example.com")
+	if len(events) != 1 || events[0].UID != "subdock-4-20260301@example.com" {
+		t.Errorf("events = %v, want only the current renewal", eventDates(events))
+	}
+}
+
+func TestBuildCalendarStable(t *testing.T) {
+	sub := calendarSubscription(1, "Netflix", "2026-03-25", true)
+	sub.UpdatedAt = time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	opts := CalendarOptions{Name: "订阅", From: date("2026-03-20"), Until: date("2026-06-01")}
+
+	first := BuildCalendar([]model.Subscription{sub}, opts)
+	if second := BuildCalendar([]model.Subscription{sub}, opts); second != first {
+		t.Error("内容不变时重新生成的日历应完全一致")
+	}
+	for _, want := range []string{"UID:subdock-1-20260425@subdock\r\n", "DTSTAMP:20260301T080000Z\r\n", "DTSTART;VALUE=DATE:20260425\r\n"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("calendar missing %q", want)
+		}
+	}
+}
+
+func TestBuildCalendarAlarms(t *testing.T) {
+	sub := calendarSubscription(1, "Netflix", "2026-03-25", false)
+	sub.RemindOffsets = "7,0,-3"
+	ics := BuildCalendar([]model.Subscription{sub}, CalendarOptions{From: date("2026-03-20"), Until: date("2026-06-01")})
+
+	var triggers []string
+	for _, line := range strings.Split(ics, "\r\n") {
+		if v, ok := strings.CutPrefix(line, "TRIGGER:"); ok {
+			triggers = append(triggers, v)
+		}
+	}
+	if got := strings.Join(triggers, ","); got != "-P7D,PT0S,P3D" {
+		t.Errorf("triggers = %s, want -P7D,PT0S,P3D", got)
+	}
+}
+
+func TestBuildCalendarFoldsMultibyteLines(t *testing.T) {
+	sub := calendarSubscription(1, strings.Repeat("网", 40), "2026-03-25", false)
+	sub.Remark = strings.Repeat("备注；", 30)
+	ics := BuildCalendar([]model.Subscription{sub}, CalendarOptions{From: date("2026-03-20"), Until: date("2026-06-01")})
+
+	if !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Error("每行应以 CRLF 结尾")
+	}
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("行超过 75 字节: %d", len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("折行拆分了多字节字符: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded == 0 {
+		t.Error("长文本应按 RFC 5545 折行")
+	}
+
+	// 去掉折行后还原完整的摘要
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:⏰ "+sub.Name+" 到期\r\n") {
+		t.Error("折行后应能还原完整的摘要")
+	}
+}
@@ -0,0 +1,693 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"subdock/internal/model"
+)
+
+// CSV 导入支持的目标字段
+const (
+	ImportFieldName          = "name"
+	ImportFieldAmount        = "amount"
+	ImportFieldCurrency      = "currency"
+	ImportFieldStartDate     = "start_date"
+	ImportFieldBillingType   = "billing_type" // recurring、one_time 或 lifetime
+	ImportFieldCycle         = "cycle"        // 合并写法，如 "1 month"、"yearly"、"每季度"
+	ImportFieldCycleValue    = "cycle_value"
+	ImportFieldCycleUnit     = "cycle_unit"
+	ImportFieldCycleRule     = "cycle_rule" // month_days 与 rrule 周期的规则
+	ImportFieldExpireDate    = "expire_date"
+	ImportFieldSupportEnd    = "support_end_date"
+	ImportFieldAutoRenew     = "auto_renew"
+	ImportFieldRemindDays    = "remind_days" // 旧版提前提醒天数，导入时换算为提醒偏移
+	ImportFieldRemindOffsets = "remind_offsets"
+	ImportFieldTimezone      = "timezone"
+	ImportFieldStatus        = "status"
+	ImportFieldCategory      = "category"
+	ImportFieldTags          = "tags"
+	ImportFieldRemark        = "remark"
+)
+
+// importFieldAliases 未指定列映射时，按表头自动匹配的别名（小写比较）
+var importFieldAliases = map[string][]string{
+	ImportFieldName:          {"name", "名称", "订阅名称", "service", "title"},
+	ImportFieldAmount:        {"amount", "金额", "price", "cost", "价格", "费用"},
+	ImportFieldCurrency:      {"currency", "币种", "货币"},
+	ImportFieldStartDate:     {"start_date", "start", "开始日期", "开始时间", "start date"},
+	ImportFieldBillingType:   {"billing_type", "计费类型", "billing type"},
+	ImportFieldCycle:         {"cycle", "周期", "billing cycle", "frequency", "计费周期"},
+	ImportFieldCycleValue:    {"cycle_value", "周期数"},
+	ImportFieldCycleUnit:     {"cycle_unit", "周期单位"},
+	ImportFieldCycleRule:     {"cycle_rule", "周期规则", "rrule"},
+	ImportFieldExpireDate:    {"expire_date", "expire", "expiry", "到期日期", "到期时间", "next billing", "expire date"},
+	ImportFieldSupportEnd:    {"support_end_date", "支持截止日期", "更新截止日期", "support until", "updates until"},
+	ImportFieldAutoRenew:     {"auto_renew", "自动续订", "auto renew"},
+	ImportFieldRemindDays:    {"remind_days", "提醒天数", "提前提醒"},
+	ImportFieldRemindOffsets: {"remind_offsets", "提醒偏移", "提醒时间"},
+	ImportFieldTimezone:      {"timezone", "时区", "time zone"},
+	ImportFieldStatus:        {"status", "状态"},
+	ImportFieldCategory:      {"category", "分类", "类别"},
+	ImportFieldTags:          {"tags", "标签", "tag"},
+	ImportFieldRemark:        {"remark", "备注", "note", "notes", "description"},
+}
+
+// importDateLayouts 日期格式自动识别的候选格式，按优先级排列
+var importDateLayouts = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+	"2006-1-2",
+	"2006/1/2",
+	"20060102",
+	"2006年1月2日",
+	"01/02/2006",
+	"02/01/2006",
+	"1/2/2006",
+	"2/1/2006",
+	"02.01.2006",
+	"02-01-2006",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+// currencySymbols 金额中的货币符号，较长的符号在前优先匹配
+var currencySymbols = []struct {
+	Symbol string
+	Code   string
+}{
+	{"US$", "USD"},
+	{"HK$", "HKD"},
+	{"$", "USD"},
+	{"¥", "CNY"},
+	{"￥", "CNY"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"₩", "KRW"},
+	{"₹", "INR"},
+}
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Za-z]{3}$`)
+
+// ImportError 单行单字段的校验错误
+type ImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportRow 解析后的一行数据，Present 记录 CSV 中实际提供了哪些字段
+type ImportRow struct {
+	Row            int                      `json:"row"` // 文件中的行号，表头为第 1 行
+	Name           string                   `json:"name"`
+	Amount         float64                  `json:"amount"`
+	Currency       string                   `json:"currency"`
+	StartDate      time.Time                `json:"start_date"`
+	BillingType    model.BillingType        `json:"billing_type"`
+	CycleValue     int                      `json:"cycle_value"`
+	CycleUnit      model.CycleUnit          `json:"cycle_unit"`
+	CycleRule      string                   `json:"cycle_rule"`
+	ExpireDate     *time.Time               `json:"expire_date"` // 一次性购买为空表示不会到期
+	SupportEndDate *time.Time               `json:"support_end_date"`
+	AutoRenew      bool                     `json:"auto_renew"`
+	RemindOffsets  string                   `json:"remind_offsets"`
+	Timezone       string                   `json:"timezone"`
+	Status         model.SubscriptionStatus `json:"status"`
+	Category       string                   `json:"category"`
+	Tags           []string                 `json:"tags"`
+	Remark         string                   `json:"remark"`
+	Present        map[string]bool          `json:"-"`
+	Errors         []ImportError            `json:"errors"`
+}
+
+// CSVImportOptions 导入选项
+type CSVImportOptions struct {
+	Mapping    map[string]string // 目标字段 -> CSV 表头，未指定的字段按别名自动匹配
+	DateFormat string            // 日期格式，如 YYYY-MM-DD 或 Go 布局；为空时自动识别
+}
+
+// CSVImport 解析结果
+type CSVImport struct {
+	Headers    []string          `json:"headers"`
+	Mapping    map[string]string `json:"mapping"`
+	DateFormat string            `json:"date_format"`
+	Rows       []ImportRow       `json:"rows"`
+}
+
+// ParseSubscriptionCSV 解析订阅 CSV，逐行校验并返回每行的错误
+func ParseSubscriptionCSV(r io.Reader, opts CSVImportOptions) (*CSVImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, errors.New("CSV 至少需要表头和一行数据")
+	}
+
+	headers := records[0]
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+	}
+
+	mapping, err := resolveImportMapping(headers, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := mapping[ImportFieldName]; !ok {
+		return nil, errors.New("缺少名称列（name）")
+	}
+	if _, ok := mapping[ImportFieldStartDate]; !ok {
+		return nil, errors.New("缺少开始日期列（start_date）")
+	}
+
+	columns := make(map[string]int)
+	for field, header := range mapping {
+		for i, h := range headers {
+			if strings.TrimSpace(h) == header {
+				columns[field] = i
+				break
+			}
+		}
+	}
+
+	rows := records[1:]
+	layout, err := resolveDateLayout(opts.DateFormat, rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CSVImport{
+		Headers:    headers,
+		Mapping:    mapping,
+		DateFormat: layout,
+		Rows:       []ImportRow{},
+	}
+	for i, record := range rows {
+		if isBlankRecord(record) {
+			continue
+		}
+		result.Rows = append(result.Rows, parseImportRow(i+2, record, columns, layout))
+	}
+	return result, nil
+}
+
+// csvFormulaPrefixes 表格软件会当作公式执行的单元格首字符
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// EscapeCSVCell 导出时在可能被当作公式执行的单元格前加 '，防止 CSV 公式注入
+func EscapeCSVCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// UnescapeCSVCell 还原 EscapeCSVCell 转义的单元格，使导出的文件可以原样导入
+func UnescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// ParseCycle 解析周期描述，如 "1 month"、"3 months"、"monthly"、"yearly"、"每季度"、"2年"
+func ParseCycle(s string) (int, model.CycleUnit, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, "", errors.New("周期为空")
+	}
+	s = strings.TrimPrefix(s, "每")
+	s = strings.TrimPrefix(s, "every ")
+
+	value := 1
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > 0 {
+		v, err := strconv.Atoi(s[:i])
+		if err != nil || v <= 0 {
+			return 0, "", fmt.Errorf("无效的周期: %s", s)
+		}
+		value = v
+	}
+
+	unit, multiplier, ok := parseCycleUnit(strings.TrimSpace(s[i:]))
+	if !ok {
+		return 0, "", fmt.Errorf("无法识别的周期: %s", s)
+	}
+	return value * multiplier, unit, nil
+}
+
+// ParseAmount 解析金额，支持货币符号与币种代码前后缀，如 "$9.99"、"9.99 USD"、"¥1,200"
+func ParseAmount(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, "", nil
+	}
+
+	currency := ""
+	for _, cs := range currencySymbols {
+		if strings.HasPrefix(s, cs.Symbol) || strings.HasSuffix(s, cs.Symbol) {
+			currency = cs.Code
+			s = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(s, cs.Symbol), cs.Symbol))
+			break
+		}
+	}
+	if fields := strings.Fields(s); len(fields) == 2 {
+		switch {
+		case currencyCodePattern.MatchString(fields[0]):
+			currency, s = strings.ToUpper(fields[0]), fields[1]
+		case currencyCodePattern.MatchString(fields[1]):
+			currency, s = strings.ToUpper(fields[1]), fields[0]
+		}
+	}
+
+	s = strings.ReplaceAll(s, ",", "")
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("无效的金额: %s", s)
+	}
+	if amount < 0 {
+		return 0, "", errors.New("金额不能小于 0")
+	}
+	return amount, currency, nil
+}
+
+// ConvertDateFormat 将 YYYY-MM-DD 风格的格式转换为 Go 时间布局，已是 Go 布局时原样返回
+func ConvertDateFormat(format string) string {
+	if strings.Contains(format, "2006") {
+		return format
+	}
+	return strings.NewReplacer(
+		"YYYY", "2006",
+		"yyyy", "2006",
+		"MM", "01",
+		"DD", "02",
+		"dd", "02",
+		"M", "1",
+		"D", "2",
+		"d", "2",
+	).Replace(format)
+}
+
+// resolveImportMapping 合并用户指定的列映射与按别名自动匹配的结果
+func resolveImportMapping(headers []string, custom map[string]string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	used := make(map[string]bool)
+
+	headerSet := make(map[string]bool)
+	for _, h := range headers {
+		headerSet[strings.TrimSpace(h)] = true
+	}
+
+	for field, header := range custom {
+		if _, ok := importFieldAliases[field]; !ok {
+			return nil, fmt.Errorf("未知的目标字段: %s", field)
+		}
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !headerSet[header] {
+			return nil, fmt.Errorf("CSV 中不存在列: %s", header)
+		}
+		mapping[field] = header
+		used[header] = true
+	}
+
+	for field, aliases := range importFieldAliases {
+		if _, ok := mapping[field]; ok {
+			continue
+		}
+		for _, h := range headers {
+			h = strings.TrimSpace(h)
+			if used[h] {
+				continue
+			}
+			if containsString(aliases, strings.ToLower(h)) {
+				mapping[field] = h
+				used[h] = true
+				break
+			}
+		}
+	}
+	return mapping, nil
+}
+
+// resolveDateLayout 确定日期格式：指定格式优先，否则选择能解析最多日期值的候选格式（数量相同时按优先级）
+func resolveDateLayout(format string, rows [][]string, columns map[string]int) (string, error) {
+	if format != "" {
+		return ConvertDateFormat(format), nil
+	}
+
+	var values []string
+	for _, field := range []string{ImportFieldStartDate, ImportFieldExpireDate, ImportFieldSupportEnd} {
+		col, ok := columns[field]
+		if !ok {
+			continue
+		}
+		for _, record := range rows {
+			if col < len(record) && strings.TrimSpace(record[col]) != "" {
+				values = append(values, strings.TrimSpace(record[col]))
+			}
+		}
+	}
+	if len(values) == 0 {
+		return importDateLayouts[0], nil
+	}
+
+	best, bestCount := "", 0
+	for _, layout := range importDateLayouts {
+		count := 0
+		for _, v := range values {
+			if _, err := time.Parse(layout, v); err == nil {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = layout, count
+		}
+	}
+	if bestCount > 0 {
+		return best, nil
+	}
+	return "", errors.New("无法识别日期格式，请通过 date_format 指定")
+}
+
+// parseImportRow 解析并校验一行数据
+func parseImportRow(rowNum int, record []string, columns map[string]int, layout string) ImportRow {
+	row := ImportRow{
+		Row:         rowNum,
+		BillingType: model.BillingRecurring,
+		CycleValue:  1,
+		CycleUnit:   model.CycleUnitMonth,
+		Status:      model.StatusActive,
+		Tags:        []string{},
+		Present:     make(map[string]bool),
+		Errors:      []ImportError{},
+	}
+
+	// cell 读取字段对应的单元格，第二个返回值表示 CSV 是否包含该列，单元格可以为空
+	cell := func(field string) (string, bool) {
+		col, ok := columns[field]
+		if !ok || col >= len(record) {
+			return "", false
+		}
+		return strings.TrimSpace(record[col]), true
+	}
+	// get 读取非空单元格，空单元格视为未提供
+	get := func(field string) (string, bool) {
+		v, ok := cell(field)
+		return v, ok && v != ""
+	}
+	// text 读取导出时转义过的文本单元格（名称、分类、标签与备注）并还原，空单元格表示清空
+	text := func(field string) (string, bool) {
+		v, ok := cell(field)
+		return UnescapeCSVCell(v), ok
+	}
+	fail := func(field, msg string) {
+		row.Errors = append(row.Errors, ImportError{Row: rowNum, Field: field, Message: msg})
+	}
+
+	if v, _ := text(ImportFieldName); v != "" {
+		row.Name = v
+		row.Present[ImportFieldName] = true
+	} else {
+		fail(ImportFieldName, "名称不能为空")
+	}
+
+	if v, ok := get(ImportFieldAmount); ok {
+		amount, currency, err := ParseAmount(v)
+		if err != nil {
+			fail(ImportFieldAmount, err.Error())
+		} else {
+			row.Amount = amount
+			row.Present[ImportFieldAmount] = true
+			if currency != "" {
+				row.Currency = currency
+				row.Present[ImportFieldCurrency] = true
+			}
+		}
+	}
+
+	if v, ok := get(ImportFieldCurrency); ok {
+		if !currencyCodePattern.MatchString(v) {
+			fail(ImportFieldCurrency, "币种应为 3 位字母代码")
+		} else {
+			row.Currency = strings.ToUpper(v)
+			row.Present[ImportFieldCurrency] = true
+		}
+	}
+	if row.Currency == "" {
+		row.Currency = "CNY"
+	}
+
+	if v, ok := get(ImportFieldStartDate); ok {
+		date, err := time.Parse(layout, v)
+		if err != nil {
+			fail(ImportFieldStartDate, "开始日期格式错误: "+v)
+		} else {
+			row.StartDate = model.CalendarDate(date)
+			row.Present[ImportFieldStartDate] = true
+		}
+	} else {
+		fail(ImportFieldStartDate, "开始日期不能为空")
+	}
+
+	if v, ok := get(ImportFieldExpireDate); ok {
+		date, err := time.Parse(layout, v)
+		if err != nil {
+			fail(ImportFieldExpireDate, "到期日期格式错误: "+v)
+		} else {
+			date = model.CalendarDate(date)
+			row.ExpireDate = &date
+			row.Present[ImportFieldExpireDate] = true
+		}
+	}
+
+	if v, ok := get(ImportFieldSupportEnd); ok {
+		date, err := time.Parse(layout, v)
+		if err != nil {
+			fail(ImportFieldSupportEnd, "支持截止日期格式错误: "+v)
+		} else {
+			date = model.CalendarDate(date)
+			row.SupportEndDate = &date
+			row.Present[ImportFieldSupportEnd] = true
+		}
+	}
+
+	if v, ok := get(ImportFieldBillingType); ok {
+		if billingType, valid := parseBillingType(strings.ToLower(v)); valid {
+			row.BillingType = billingType
+			row.Present[ImportFieldBillingType] = true
+		} else {
+			fail(ImportFieldBillingType, "计费类型应为 recurring、one_time 或 lifetime")
+		}
+	}
+
+	if v, ok := get(ImportFieldCycle); ok {
+		value, unit, err := ParseCycle(v)
+		if err != nil {
+			fail(ImportFieldCycle, err.Error())
+		} else {
+			row.CycleValue, row.CycleUnit = value, unit
+			row.Present[ImportFieldCycle] = true
+		}
+	}
+	// 分列写法：cycle_unit + cycle_value，优先于合并写法
+	unitText, hasUnit := get(ImportFieldCycleUnit)
+	valueText, hasValue := get(ImportFieldCycleValue)
+	if hasUnit || hasValue {
+		unit, multiplier, valid := row.CycleUnit, 1, true
+		if hasUnit {
+			unit, multiplier, valid = parseCycleUnit(strings.ToLower(unitText))
+			if !valid {
+				fail(ImportFieldCycleUnit, "无法识别的周期单位: "+unitText)
+			}
+		}
+		value := 1
+		if hasValue {
+			v, err := strconv.Atoi(valueText)
+			if err != nil || v <= 0 {
+				fail(ImportFieldCycleValue, "周期数必须为正整数")
+				valid = false
+			} else {
+				value = v
+			}
+		}
+		if valid {
+			row.CycleUnit = unit
+			row.CycleValue = value * multiplier
+			row.Present[ImportFieldCycle] = true
+		}
+	}
+	if v, ok := get(ImportFieldCycleRule); ok {
+		row.CycleRule = v
+		row.Present[ImportFieldCycle] = true
+	}
+	if row.CycleUnit.IsRuleCycle() && row.BillingType == model.BillingRecurring {
+		if err := model.ValidateCycle(row.CycleUnit, row.CycleRule); err != nil {
+			fail(ImportFieldCycleRule, err.Error())
+		}
+	}
+
+	if v, ok := get(ImportFieldAutoRenew); ok {
+		b, err := parseBool(v)
+		if err != nil {
+			fail(ImportFieldAutoRenew, err.Error())
+		} else {
+			row.AutoRenew = b
+			row.Present[ImportFieldAutoRenew] = true
+		}
+	}
+	if row.AutoRenew && row.BillingType != model.BillingRecurring {
+		fail(ImportFieldAutoRenew, model.ErrNotRenewable.Error())
+	}
+
+	if v, ok := get(ImportFieldRemindOffsets); ok {
+		offsets, err := model.NormalizeRemindOffsets(v)
+		if err != nil {
+			fail(ImportFieldRemindOffsets, err.Error())
+		} else {
+			row.RemindOffsets = offsets
+			row.Present[ImportFieldRemindOffsets] = true
+		}
+	} else if v, ok := get(ImportFieldRemindDays); ok {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			fail(ImportFieldRemindDays, "提醒天数必须为非负整数")
+		} else {
+			row.RemindOffsets = model.LegacyRemindOffsets(days)
+			row.Present[ImportFieldRemindOffsets] = true
+		}
+	}
+
+	if v, ok := cell(ImportFieldTimezone); ok {
+		if err := model.ValidateTimezone(v); err != nil {
+			fail(ImportFieldTimezone, err.Error())
+		} else {
+			row.Timezone = v
+			row.Present[ImportFieldTimezone] = true
+		}
+	}
+
+	if v, ok := get(ImportFieldStatus); ok {
+		switch model.SubscriptionStatus(strings.ToLower(v)) {
+		case model.StatusActive, model.StatusPaused, model.StatusCancelled:
+			row.Status = model.SubscriptionStatus(strings.ToLower(v))
+			row.Present[ImportFieldStatus] = true
+		default:
+			fail(ImportFieldStatus, "状态应为 active、paused 或 cancelled")
+		}
+	}
+
+	if v, ok := text(ImportFieldCategory); ok {
+		row.Category = v
+		row.Present[ImportFieldCategory] = true
+	}
+
+	if v, ok := text(ImportFieldTags); ok {
+		row.Tags = splitTags(v)
+		row.Present[ImportFieldTags] = true
+	}
+
+	if v, ok := text(ImportFieldRemark); ok {
+		row.Remark = v
+		row.Present[ImportFieldRemark] = true
+	}
+
+	if row.ExpireDate != nil && !row.StartDate.IsZero() && row.ExpireDate.Before(row.StartDate) {
+		fail(ImportFieldExpireDate, "到期日期早于开始日期")
+	}
+
+	return row
+}
+
+// parseBillingType 解析计费类型，兼容常见中文写法
+func parseBillingType(s string) (model.BillingType, bool) {
+	switch strings.TrimSpace(s) {
+	case "recurring", "subscription", "周期", "订阅", "周期订阅":
+		return model.BillingRecurring, true
+	case "one_time", "one-time", "onetime", "一次性", "一次性购买", "买断":
+		return model.BillingOneTime, true
+	case "lifetime", "perpetual", "永久", "终身", "永久授权":
+		return model.BillingLifetime, true
+	}
+	return "", false
+}
+
+// parseCycleUnit 解析周期单位，返回单位及数值倍数
+func parseCycleUnit(s string) (model.CycleUnit, int, bool) {
+	switch strings.TrimSpace(s) {
+	case "d", "day", "days", "daily", "日", "天":
+		return model.CycleUnitDay, 1, true
+	case "w", "week", "weeks", "weekly", "周", "星期", "个星期":
+		return model.CycleUnitWeek, 1, true
+	case "business_day", "business_days", "business day", "business days", "工作日", "个工作日":
+		return model.CycleUnitBusinessDay, 1, true
+	case "month_days", "每月固定日期":
+		return model.CycleUnitMonthDays, 1, true
+	case "rrule":
+		return model.CycleUnitRRule, 1, true
+	case "m", "mo", "month", "months", "monthly", "月", "个月":
+		return model.CycleUnitMonth, 1, true
+	case "q", "quarter", "quarters", "quarterly", "季", "季度", "个季度":
+		return model.CycleUnitQuarter, 1, true
+	case "half_year", "half year", "half-year", "half_yearly", "half yearly", "half-yearly",
+		"semiannual", "semi-annual", "semiannually", "semi-annually", "半年":
+		return model.CycleUnitHalfYear, 1, true
+	case "y", "yr", "year", "years", "yearly", "annual", "annually", "年", "年度":
+		return model.CycleUnitYear, 1, true
+	}
+	return "", 0, false
+}
+
+// parseBool 解析布尔值，支持 true/false、yes/no、1/0、是/否
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "y", "1", "是", "开启", "on":
+		return true, nil
+	case "false", "no", "n", "0", "否", "关闭", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("无效的布尔值: %s", s)
+}
+
+// splitTags 拆分标签，支持 ; | ， 、 分隔
+func splitTags(s string) []string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == '|' || r == '，' || r == '、' || r == ','
+	})
+	tags := []string{}
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" && !containsString(tags, p) {
+			tags = append(tags, p)
+		}
+	}
+	return tags
+}
+
+// isBlankRecord 判断是否为空行
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimFunc(v, unicode.IsSpace) != "" {
+			return false
+		}
+	}
+	return true
+}
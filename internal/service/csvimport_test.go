@@ -0,0 +1,127 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"subdock/internal/model"
+)
+
+func TestParseCycle(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantValue int
+		wantUnit  model.CycleUnit
+		wantErr   bool
+	}{
+		{"monthly", 1, model.CycleUnitMonth, false},
+		{"3 months", 3, model.CycleUnitMonth, false},
+		{"Yearly", 1, model.CycleUnitYear, false},
+		{"annually", 1, model.CycleUnitYear, false},
+		{"quarterly", 1, model.CycleUnitQuarter, false},
+		{"half-yearly", 1, model.CycleUnitHalfYear, false},
+		{"weekly", 1, model.CycleUnitWeek, false},
+		{"daily", 1, model.CycleUnitDay, false},
+		{"每季度", 1, model.CycleUnitQuarter, false},
+		{"2年", 2, model.CycleUnitYear, false},
+		{"only", 0, "", true},
+		{"fly", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		value, unit, err := ParseCycle(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCycle(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (value != tt.wantValue || unit != tt.wantUnit) {
+			t.Errorf("ParseCycle(%q) = %d %s, want %d %s", tt.in, value, unit, tt.wantValue, tt.wantUnit)
+		}
+	}
+}
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := map[string]string{
+		"Netflix":       "Netflix",
+		"=HYPERLINK(1)": "'=HYPERLINK(1)",
+		"+1":            "'+1",
+		"-cmd":          "'-cmd",
+		"@SUM(A1)":      "'@SUM(A1)",
+		"":              "",
+		"it's fine":     "it's fine",
+	}
+	for in, want := range tests {
+		got := EscapeCSVCell(in)
+		if got != want {
+			t.Errorf("EscapeCSVCell(%q) = %q, want %q", in, got, want)
+		}
+		if back := UnescapeCSVCell(got); back != in {
+			t.Errorf("UnescapeCSVCell(%q) = %q, want %q", got, back, in)
+		}
+	}
+}
+
+func TestParseSubscriptionCSVCells(t *testing.T) {
+	data := "name,amount,start_date,expire_date,cycle_rule,timezone,category,tags,remark\n" +
+		"'=Netflix,10,2026-03-01,2026-04-15,,Asia/Shanghai,'-分类,'@a;b,'+备注\n" +
+		"iCloud,'-5,2026-03-01,,'=x,,,,\n" +
+		"Spotify,5,2026-03-01,,,Mars/Base,,,\n"
+	parsed, err := ParseSubscriptionCSV(strings.NewReader(data), CSVImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Rows) != 3 {
+		t.Fatalf("len(rows) = %d, want 3", len(parsed.Rows))
+	}
+
+	// 导出时转义的名称、分类、标签与备注导入时还原
+	first := parsed.Rows[0]
+	if len(first.Errors) > 0 {
+		t.Fatalf("row 2 errors = %+v", first.Errors)
+	}
+	if first.Name != "=Netflix" || first.Category != "-分类" || strings.Join(first.Tags, ";") != "@a;b" || first.Remark != "+备注" {
+		t.Errorf("row 2 = %q %q %v %q", first.Name, first.Category, first.Tags, first.Remark)
+	}
+	if first.Timezone != "Asia/Shanghai" || !first.ExpireDate.Equal(time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("row 2 timezone = %q, expire_date = %v", first.Timezone, first.ExpireDate)
+	}
+
+	// 其他列不还原转义；空的文本单元格表示清空，空的其他单元格视为未提供
+	second := parsed.Rows[1]
+	if !hasImportError(second, ImportFieldAmount) || second.CycleRule != "'=x" {
+		t.Errorf("row 3 errors = %+v, cycle_rule = %q", second.Errors, second.CycleRule)
+	}
+	for _, field := range []string{ImportFieldCategory, ImportFieldTags, ImportFieldRemark, ImportFieldTimezone} {
+		if !second.Present[field] {
+			t.Errorf("row 3 应提供空的 %s", field)
+		}
+	}
+	if second.Present[ImportFieldExpireDate] {
+		t.Error("row 3 空的到期日期应视为未提供")
+	}
+
+	if !hasImportError(parsed.Rows[2], ImportFieldTimezone) {
+		t.Errorf("row 4 errors = %+v, want timezone error", parsed.Rows[2].Errors)
+	}
+
+	// 缺少的列不视为提供
+	parsed, err = ParseSubscriptionCSV(strings.NewReader("name,amount,start_date\nNetflix,10,2026-03-01\n"), CSVImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{ImportFieldCategory, ImportFieldTags, ImportFieldRemark, ImportFieldTimezone} {
+		if parsed.Rows[0].Present[field] {
+			t.Errorf("缺少 %s 列时不应视为提供", field)
+		}
+	}
+}
+
+func hasImportError(row ImportRow, field string) bool {
+	for _, e := range row.Errors {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"subdock/internal/model"
+)
+
+// 摘要通知相关设置项
+const (
+	SettingNotifyMode     = "notify_mode"     // individual 或 digest
+	SettingDigestCadence  = "digest_cadence"  // daily 或 weekly
+	SettingDigestSchedule = "digest_schedule" // 摘要发送计划 cron 表达式，为空时按 digest_cadence 取默认值
+	SettingDigestDays     = "digest_days"     // 摘要列出未来 N 天内到期的订阅
+)
+
+// 通知方式
+const (
+	NotifyModeIndividual = "individual" // 每个订阅单独发送提醒
+	NotifyModeDigest     = "digest"     // 按摘要计划汇总发送
+)
+
+// 摘要频率
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// 摘要默认发送计划：每天 9 点 / 每周一 9 点
+const (
+	DefaultDailyDigestSchedule  = "0 9 * * *"
+	DefaultWeeklyDigestSchedule = "0 9 * * 1"
+)
+
+// DefaultDigestDays 摘要默认列出未来 7 天内到期的订阅
+const DefaultDigestDays = 7
+
+// maxDigestDays 摘要到期范围的天数上限
+const maxDigestDays = 365
+
+// EffectiveDigestSchedule 实际使用的摘要发送计划，未配置或配置无效时按频率取默认值
+func EffectiveDigestSchedule(schedule, cadence string) string {
+	if _, err := ParseNotifySchedule(schedule); err == nil {
+		return strings.TrimSpace(schedule)
+	}
+	if cadence == DigestWeekly {
+		return DefaultWeeklyDigestSchedule
+	}
+	return DefaultDailyDigestSchedule
+}
+
+// DigestLookback 摘要回顾的时长：每日摘要回顾 1 天，每周摘要回顾 7 天
+func DigestLookback(cadence string) time.Duration {
+	if cadence == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ParseDigestDays 解析摘要到期范围天数（1-365）
+func ParseDigestDays(s string) (int, error) {
+	days, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || days < 1 || days > maxDigestDays {
+		return 0, fmt.Errorf("摘要天数应为 1-%d 的整数", maxDigestDays)
+	}
+	return days, nil
+}
+
+// DigestItem 摘要中即将到期的一项
+type DigestItem struct {
+	Subscription model.Subscription `json:"subscription"`
+	Date         time.Time          `json:"date"`
+	SupportEnd   bool               `json:"support_end"` // 是否为支持/更新截止日期
+}
+
+// DigestRenewal 摘要中的一条自动续订记录
+type DigestRenewal struct {
+	Name    string                    `json:"name"`
+	Renewal model.SubscriptionRenewal `json:"renewal"`
+}
+
+// Digest 通知摘要
+type Digest struct {
+	Cadence     string          `json:"cadence"`
+	Date        time.Time       `json:"date"` // 生成摘要当天
+	Days        int             `json:"days"`
+	Upcoming    []DigestItem    `json:"upcoming"`
+	Overdue     []DigestItem    `json:"overdue"` // 已过期、未续订且未确认的订阅，Date 为到期日期
+	Renewals    []DigestRenewal `json:"renewals"`
+	Currency    string          `json:"currency"`
+	Projected   float64         `json:"projected"` // 本月已发生支出 + 本月剩余预计扣费
+	Unconverted []string        `json:"unconverted"`
+}
+
+// BuildDigest 生成摘要：未来 days 天内（含今天）到期或支持截止且未暂停、未确认提醒的订阅、已过期未续订的订阅、since 之后的自动续订，以及本月预计支出
+// now 的时区决定“今天”和“本月”，各订阅的到期日期按日历日期比较
+func BuildDigest(cadence string, now, since time.Time, days int, currency string) (*Digest, error) {
+	today := model.CalendarDate(now)
+	until := today.AddDate(0, 0, days)
+	digest := &Digest{
+		Cadence:     cadence,
+		Date:        today,
+		Days:        days,
+		Upcoming:    []DigestItem{},
+		Overdue:     []DigestItem{},
+		Renewals:    []DigestRenewal{},
+		Currency:    strings.ToUpper(currency),
+		Unconverted: []string{},
+	}
+
+	var subscriptions []model.Subscription
+	if err := model.GetDB().Where("status = ?", model.StatusActive).Order("expire_date asc").Order("id asc").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	for _, sub := range subscriptions {
+		if _, ok := sub.OverdueDays(today); ok && !sub.IsAcknowledged(sub.ExpireDate) {
+			digest.Overdue = append(digest.Overdue, DigestItem{Subscription: sub, Date: model.CalendarDate(sub.ExpireDate)})
+		}
+		for _, date := range sub.ReminderDates() {
+			// 与单独提醒一致，已暂停或已确认的提醒不列出
+			if date.Before(today) || date.After(until) || sub.IsSnoozed(today) || sub.IsAcknowledged(date) {
+				continue
+			}
+			digest.Upcoming = append(digest.Upcoming, DigestItem{Subscription: sub, Date: date, SupportEnd: sub.IsSupportEnd(date)})
+		}
+	}
+	sort.SliceStable(digest.Upcoming, func(i, j int) bool {
+		return digest.Upcoming[i].Date.Before(digest.Upcoming[j].Date)
+	})
+
+	var renewals []model.SubscriptionRenewal
+	if err := model.GetDB().Where("renewed_at > ? AND auto = ?", since, true).Order("renewed_at asc").Order("id asc").Find(&renewals).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string)
+	for _, sub := range subscriptions {
+		names[sub.ID] = sub.Name
+	}
+	for _, r := range renewals {
+		name, ok := names[r.SubscriptionID]
+		if !ok {
+			var sub model.Subscription
+			if err := model.GetDB().Unscoped().Select("name").First(&sub, r.SubscriptionID).Error; err == nil {
+				name = sub.Name
+			}
+		}
+		digest.Renewals = append(digest.Renewals, DigestRenewal{Name: name, Renewal: r})
+	}
+
+	// 本月预计支出与月度总预算的预计支出口径一致
+	status, err := EvaluateBudget(model.Budget{Period: model.BudgetPeriodMonth, Currency: digest.Currency}, now)
+	if err != nil {
+		return nil, err
+	}
+	digest.Projected = status.Projected
+	digest.Unconverted = status.Unconverted
+	return digest, nil
+}
+
+// FormatDigest 格式化摘要消息
+func FormatDigest(d *Digest) string {
+	title := "📊 每日摘要"
+	if d.Cadence == DigestWeekly {
+		title = "📊 每周摘要"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s（%s）\n", title, d.Date.Format("2006-01-02"))
+
+	fmt.Fprintf(&b, "\n⏰ 未来 %d 天到期（%d）\n", d.Days, len(d.Upcoming))
+	if len(d.Upcoming) == 0 {
+		b.WriteString("暂无\n")
+	}
+	for _, item := range d.Upcoming {
+		label := "到期"
+		if item.SupportEnd {
+			label = "支持到期"
+		}
+		fmt.Fprintf(&b, "- %s %s %s  %.2f %s\n", item.Date.Format("01-02"), item.Subscription.Name, label,
+			item.Subscription.Amount, item.Subscription.Currency)
+	}
+
+	if len(d.Overdue) > 0 {
+		fmt.Fprintf(&b, "\n⚠️ 已过期（%d）\n", len(d.Overdue))
+		for _, item := range d.Overdue {
+			fmt.Fprintf(&b, "- %s 已过期 %d 天（%s 到期）\n", item.Subscription.Name,
+				int(d.Date.Sub(item.Date).Hours()/24), item.Date.Format("2006-01-02"))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n🔄 自动续订（%d）\n", len(d.Renewals))
+	if len(d.Renewals) == 0 {
+		b.WriteString("暂无\n")
+	}
+	for _, r := range d.Renewals {
+		fmt.Fprintf(&b, "- %s  %.2f %s，新到期日期 %s\n", r.Name, r.Renewal.Amount, r.Renewal.Currency,
+			r.Renewal.NewExpireDate.Format("2006-01-02"))
+	}
+
+	fmt.Fprintf(&b, "\n💰 本月预计支出: %.2f %s", d.Projected, d.Currency)
+	if len(d.Unconverted) > 0 {
+		fmt.Fprintf(&b, "（未计入缺少汇率的币种: %s）", strings.Join(d.Unconverted, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"subdock/internal/model"
+)
+
+func TestEffectiveDigestSchedule(t *testing.T) {
+	tests := []struct {
+		schedule, cadence, want string
+	}{
+		{"", DigestDaily, DefaultDailyDigestSchedule},
+		{"", DigestWeekly, DefaultWeeklyDigestSchedule},
+		{"invalid", DigestWeekly, DefaultWeeklyDigestSchedule},
+		{" 30 8 * * 5 ", DigestWeekly, "30 8 * * 5"},
+	}
+	for _, tt := range tests {
+		if got := EffectiveDigestSchedule(tt.schedule, tt.cadence); got != tt.want {
+			t.Errorf("EffectiveDigestSchedule(%q, %q) = %q, want %q", tt.schedule, tt.cadence, got, tt.want)
+		}
+	}
+}
+
+func TestParseDigestDays(t *testing.T) {
+	for _, s := range []string{"", "0", "-1", "366", "abc"} {
+		if _, err := ParseDigestDays(s); err == nil {
+			t.Errorf("ParseDigestDays(%q) 应返回错误", s)
+		}
+	}
+	if got, err := ParseDigestDays(" 14 "); err != nil || got != 14 {
+		t.Errorf("ParseDigestDays(14) = %d, %v", got, err)
+	}
+}
+
+func TestFormatDigest(t *testing.T) {
+	d := &Digest{
+		Cadence: DigestWeekly,
+		Date:    time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC),
+		Days:    7,
+		Upcoming: []DigestItem{
+			{Subscription: model.Subscription{Name: "Netflix", Amount: 15, Currency: "USD"}, Date: time.Date(2026, 3, 22, 0, 0, 0, 0, time.UTC)},
+		},
+		Renewals:    []DigestRenewal{},
+		Currency:    "CNY",
+		Projected:   123.4,
+		Unconverted: []string{"JPY"},
+	}
+
+	got := FormatDigest(d)
+	for _, want := range []string{"每周摘要（2026-03-20）", "03-22 Netflix 到期  15.00 USD", "🔄 自动续订（0）\n暂无", "本月预计支出: 123.40 CNY", "JPY"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatDigest() 缺少 %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"subdock/internal/model"
+)
+
+// 预测扣费类型
+const (
+	ChargeAutoRenew = "auto_renew" // 自动续订，按周期持续扣费
+	ChargeManual    = "manual"     // 未开启自动续订，仅预测下一次到期时的续费
+	ChargeOneTime   = "one_time"   // 一次性购买或永久授权，在开始日期扣费
+)
+
+// ForecastCharge 预计的一笔扣费
+type ForecastCharge struct {
+	SubscriptionID uint      `json:"subscription_id"`
+	Name           string    `json:"name"`
+	Date           time.Time `json:"date"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+	AmountBase     *float64  `json:"amount_base"` // 换算为目标币种，缺少汇率时为 null
+	Kind           string    `json:"kind"`
+}
+
+// ForecastMonth 单月预计扣费汇总
+type ForecastMonth struct {
+	Month       string             `json:"month"` // YYYY-MM
+	Count       int                `json:"count"`
+	Totals      map[string]float64 `json:"totals"` // 按原币种汇总
+	TotalBase   float64            `json:"total_base"`
+	Unconverted []string           `json:"unconverted"`
+}
+
+// Forecast 现金流预测结果
+type Forecast struct {
+	From     time.Time        `json:"from"`
+	Until    time.Time        `json:"until"`
+	Currency string           `json:"currency"`
+	Charges  []ForecastCharge `json:"charges"`
+	Months   []ForecastMonth  `json:"months"`
+}
+
+// ProjectCharges 预测订阅在 [from, until) 区间内的扣费日期
+// 仅 active 状态的订阅参与预测；开启自动续订的订阅按周期持续扣费，
+// 已过期的自动续订订阅视为在 from 当天续费；未开启自动续订的订阅只预测下一次到期续费，已过期则不再预测；
+// 一次性购买与永久授权只在开始日期扣费一次
+func ProjectCharges(sub *model.Subscription, from, until time.Time) []time.Time {
+	if sub.Status != "" && sub.Status != model.StatusActive {
+		return nil
+	}
+	if !sub.IsRecurring() {
+		start := model.CalendarDate(sub.StartDate)
+		if start.Before(from) || !start.Before(until) {
+			return nil
+		}
+		return []time.Time{start}
+	}
+
+	next := model.CalendarDate(sub.ExpireDate)
+	if !sub.AutoRenew {
+		if next.Before(from) || !next.Before(until) {
+			return nil
+		}
+		return []time.Time{next}
+	}
+
+	cycle := *sub
+	if cycle.CycleValue <= 0 {
+		cycle.CycleValue = 1
+	}
+	if next.Before(from) {
+		next = from
+	}
+
+	var dates []time.Time
+	for ok := true; ok && next.Before(until); next, ok = cycle.NextCycleDate(next) {
+		dates = append(dates, next)
+	}
+	return dates
+}
+
+// BuildForecast 预测未来 months 个月内的扣费，并按月汇总，从 loc 时区下的今天起算
+func BuildForecast(months int, currency string, loc *time.Location) (*Forecast, error) {
+	var subscriptions []model.Subscription
+	if err := model.GetDB().Where("status = ?", model.StatusActive).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	target := strings.ToUpper(currency)
+	from := model.Today(loc)
+	until := from.AddDate(0, months, 0)
+
+	forecast := &Forecast{
+		From:     from,
+		Until:    until,
+		Currency: target,
+		Charges:  []ForecastCharge{},
+		Months:   []ForecastMonth{},
+	}
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		kind := ChargeManual
+		if !sub.IsRecurring() {
+			kind = ChargeOneTime
+		} else if sub.AutoRenew {
+			kind = ChargeAutoRenew
+		}
+		for _, date := range ProjectCharges(sub, from, until) {
+			charge := ForecastCharge{
+				SubscriptionID: sub.ID,
+				Name:           sub.Name,
+				Date:           date,
+				Amount:         sub.Amount,
+				Currency:       sub.Currency,
+				Kind:           kind,
+			}
+			if converted, err := ConvertAmount(sub.Amount, sub.Currency, target, date); err == nil {
+				v := roundMoney(converted)
+				charge.AmountBase = &v
+			}
+			forecast.Charges = append(forecast.Charges, charge)
+		}
+	}
+
+	sort.SliceStable(forecast.Charges, func(i, j int) bool {
+		return forecast.Charges[i].Date.Before(forecast.Charges[j].Date)
+	})
+
+	// 按月汇总，区间内没有扣费的月份也返回
+	index := make(map[string]int)
+	for m := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); m.Before(until); m = m.AddDate(0, 1, 0) {
+		key := m.Format("2006-01")
+		index[key] = len(forecast.Months)
+		forecast.Months = append(forecast.Months, ForecastMonth{
+			Month:       key,
+			Totals:      map[string]float64{},
+			Unconverted: []string{},
+		})
+	}
+
+	for _, charge := range forecast.Charges {
+		i, ok := index[charge.Date.UTC().Format("2006-01")]
+		if !ok {
+			continue
+		}
+		month := &forecast.Months[i]
+		month.Count++
+		month.Totals[charge.Currency] = roundMoney(month.Totals[charge.Currency] + charge.Amount)
+		if charge.AmountBase != nil {
+			month.TotalBase = roundMoney(month.TotalBase + *charge.AmountBase)
+		} else if !containsString(month.Unconverted, charge.Currency) {
+			month.Unconverted = append(month.Unconverted, charge.Currency)
+		}
+	}
+
+	return forecast, nil
+}
+
+// containsString 判断切片中是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
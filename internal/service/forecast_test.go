@@ -0,0 +1,138 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+func TestProjectCharges(t *testing.T) {
+	from, until := date("2026-03-20"), date("2026-06-20")
+	monthly := func(expire string, autoRenew bool) model.Subscription {
+		return model.Subscription{
+			StartDate:   date(expire).AddDate(0, -1, 0),
+			BillingType: model.BillingRecurring,
+			CycleValue:  1,
+			CycleUnit:   model.CycleUnitMonth,
+			AnchorDay:   date(expire).Day(),
+			ExpireDate:  date(expire),
+			AutoRenew:   autoRenew,
+			Status:      model.StatusActive,
+		}
+	}
+	yearly := monthly("2026-04-01", true)
+	yearly.CycleUnit = model.CycleUnitYear
+	cancelled := monthly("2026-03-25", true)
+	cancelled.Status = model.StatusCancelled
+	oneTime := monthly("2026-04-01", false)
+	oneTime.BillingType = model.BillingOneTime
+	oneTime.StartDate = date("2026-04-01")
+	pastOneTime := oneTime
+	pastOneTime.StartDate = date("2026-03-01")
+
+	tests := []struct {
+		name string
+		sub  model.Subscription
+		want []string
+	}{
+		{"自动续订按周期扣费直到区间结束", monthly("2026-03-25", true), []string{"2026-03-25", "2026-04-25", "2026-05-25"}},
+		{"区间结束当天不包含", monthly("2026-03-20", true), []string{"2026-03-20", "2026-04-20", "2026-05-20"}},
+		{"已过期的自动续订从今天起算", monthly("2026-03-01", true), []string{"2026-03-20", "2026-04-20", "2026-05-20"}},
+		{"年付在区间内只扣一次", yearly, []string{"2026-04-01"}},
+		{"手动续订只预测下一次到期", monthly("2026-04-10", false), []string{"2026-04-10"}},
+		{"手动续订已过期不再预测", monthly("2026-03-01", false), nil},
+		{"到期日超出区间", monthly("2026-07-01", false), nil},
+		{"已取消的订阅不预测", cancelled, nil},
+		{"一次性购买在开始日期扣费", oneTime, []string{"2026-04-01"}},
+		{"一次性购买早于区间", pastOneTime, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range ProjectCharges(&tt.sub, from, until) {
+				got = append(got, d.Format("2006-01-02"))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ProjectCharges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildForecastHorizon(t *testing.T) {
+	resetSubscriptions(t)
+	resetRates(t)
+	// UTC 3 月 19 日 20:00 即上海 3 月 20 日
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 19, 20, 0, 0, 0, time.UTC))))
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub := createSubscription(t, "Netflix", 10, "2026-03-20")
+	if err := model.GetDB().Model(sub).Update("auto_renew", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	annual := createSubscription(t, "Domain", 100, "2026-04-05")
+	if err := model.GetDB().Model(annual).Updates(map[string]interface{}{"cycle_unit": model.CycleUnitYear, "currency": "USD"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		months int
+		loc    *time.Location
+		from   string
+		until  string
+		counts []int
+	}{
+		{"一个月", 1, shanghai, "2026-03-20", "2026-04-20", []int{1, 1}},
+		{"三个月", 3, shanghai, "2026-03-20", "2026-06-20", []int{1, 2, 1, 0}},
+		{"按时区计算今天", 1, time.UTC, "2026-03-19", "2026-04-19", []int{1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast, err := BuildForecast(tt.months, "cny", tt.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !forecast.From.Equal(date(tt.from)) || !forecast.Until.Equal(date(tt.until)) || forecast.Currency != "CNY" {
+				t.Errorf("range = %s - %s %s, want %s - %s", forecast.From.Format("2006-01-02"), forecast.Until.Format("2006-01-02"), forecast.Currency, tt.from, tt.until)
+			}
+			var counts []int
+			for _, m := range forecast.Months {
+				counts = append(counts, m.Count)
+			}
+			if len(counts) != len(tt.counts) {
+				t.Fatalf("month counts = %v, want %v", counts, tt.counts)
+			}
+			for i := range counts {
+				if counts[i] != tt.counts[i] {
+					t.Errorf("month counts = %v, want %v", counts, tt.counts)
+					break
+				}
+			}
+			for i := 1; i < len(forecast.Charges); i++ {
+				if forecast.Charges[i].Date.Before(forecast.Charges[i-1].Date) {
+					t.Errorf("charges 未按日期排序: %+v", forecast.Charges)
+				}
+			}
+		})
+	}
+
+	// 缺少汇率的扣费只计入原币种汇总
+	forecast, err := BuildForecast(1, "CNY", shanghai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	april := forecast.Months[1]
+	if april.Month != "2026-04" || april.Totals["USD"] != 100 || april.TotalBase != 0 || len(april.Unconverted) != 1 {
+		t.Errorf("april = %+v", april)
+	}
+	if march := forecast.Months[0]; march.Totals["CNY"] != 10 || march.TotalBase != 10 {
+		t.Errorf("march = %+v", march)
+	}
+}
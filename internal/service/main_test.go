@@ -0,0 +1,86 @@
+package service
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/config"
+	"subdock/internal/model"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "subdock-service-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("DATA_DIR", dir)
+	config.Load()
+	log.SetOutput(io.Discard)
+	if _, err := model.InitDB(); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// resetSubscriptions 清空订阅、续订记录、分类与标签
+func resetSubscriptions(t *testing.T) {
+	t.Helper()
+	db := model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true})
+	for _, stmt := range []string{
+		"DELETE FROM subscription_tags",
+		"DELETE FROM subscription_renewals",
+		"DELETE FROM subscriptions",
+		"DELETE FROM tags",
+		"DELETE FROM categories",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// createSubscription 创建按月订阅，开始日期为到期日期前一个月
+func createSubscription(t *testing.T, name string, amount float64, expire string) *model.Subscription {
+	t.Helper()
+	sub := &model.Subscription{
+		Name:          name,
+		Amount:        amount,
+		Currency:      "CNY",
+		StartDate:     date(expire).AddDate(0, -1, 0),
+		CycleValue:    1,
+		CycleUnit:     model.CycleUnitMonth,
+		ExpireDate:    date(expire),
+		AnchorDay:     date(expire).Day(),
+		RemindOffsets: "0",
+		Status:        model.StatusActive,
+	}
+	if err := model.GetDB().Create(sub).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func reload(t *testing.T, id uint) model.Subscription {
+	t.Helper()
+	var sub model.Subscription
+	if err := model.GetDB().First(&sub, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
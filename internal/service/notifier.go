@@ -1,14 +1,31 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"time"
 )
 
+// 通知事件类型，通知渠道据此区分处理（如 Bark 按事件类型分组）
+const (
+	NotifyEventExpire     = "expire"      // 即将到期或今天到期
+	NotifyEventExpired    = "expired"     // 已过期且未续订
+	NotifyEventSupportEnd = "support_end" // 支持/更新截止
+	NotifyEventBudget     = "budget"      // 预算告警
+	NotifyEventDigest     = "digest"      // 通知摘要
+	NotifyEventTest       = "test"        // 测试通知
+)
+
+// Notification 一条待发送的通知
+type Notification struct {
+	Event    string                  // 事件类型，取值见 NotifyEvent* 常量
+	Title    string                  // 标题，Bark 通知使用
+	Message  string                  // 正文
+	Keyboard *TelegramInlineKeyboard // Telegram 消息附带的按钮，为空时不附带
+}
+
 // Notifier 通知服务
 type Notifier struct {
 	client *http.Client
@@ -21,36 +38,19 @@ func NewNotifier() *Notifier {
 	}
 }
 
-// SendTelegram 发送 Telegram 消息
-func (n *Notifier) SendTelegram(botToken, chatID, message string) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
-
-	payload := map[string]string{
-		"chat_id": chatID,
-		"text":    message,
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("序列化请求失败: %w", err)
-	}
-
-	resp, err := n.client.Post(apiURL, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("发送请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Telegram API 返回错误: %d", resp.StatusCode)
-	}
-
-	return nil
+// SendTelegram 发送 Telegram 消息，keyboard 为空时不附带按钮；apiURL 为空时使用官方地址
+func (n *Notifier) SendTelegram(apiURL, botToken, chatID, message string, keyboard *TelegramInlineKeyboard) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.client.Timeout)
+	defer cancel()
+	return NewTelegramClient(apiURL, botToken).SendMessage(ctx, chatID, message, keyboard)
 }
 
-// SendBark 发送 Bark 通知
-func (n *Notifier) SendBark(barkURL, title, message string) error {
+// SendBark 发送 Bark 通知，group 不为空时按该分组（通常为事件类型）归类
+func (n *Notifier) SendBark(barkURL, title, message, group string) error {
 	fullURL := fmt.Sprintf("%s/%s/%s", barkURL, url.PathEscape(title), url.PathEscape(message))
+	if group != "" {
+		fullURL += "?group=" + url.QueryEscape(group)
+	}
 
 	resp, err := n.client.Get(fullURL)
 	if err != nil {
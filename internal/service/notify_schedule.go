@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// 通知计划相关设置项
+const (
+	SettingNotifySchedule = "notify_schedule" // cron 表达式，为空时由旧版 notify_hours 换算
+	SettingNotifyHours    = "notify_hours"    // 旧版通知时段，逗号分隔的小时
+	SettingQuietHours     = "quiet_hours"     // 免打扰时段，如 22:00-08:00，为空表示不启用
+)
+
+// DefaultNotifySchedule 未配置时的通知计划：每天 9 点
+const DefaultNotifySchedule = "0 9 * * *"
+
+// maxScheduleSteps 遍历计划触发时间的步数上限，避免每分钟触发的计划在长区间内遍历过久
+const maxScheduleSteps = 10000
+
+// ParseNotifySchedule 解析通知计划，支持标准 5 段 cron 表达式（分 时 日 月 周）与 @daily 等描述符
+// 计划按订阅所在时区计算，不支持在表达式中指定 CRON_TZ
+func ParseNotifySchedule(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("通知计划不能为空")
+	}
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		return nil, errors.New("通知计划按订阅时区计算，不支持 CRON_TZ")
+	}
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的通知计划: %v", err)
+	}
+	return schedule, nil
+}
+
+// ParseNotifyHours 解析旧版通知时段（逗号分隔的小时，0-23），返回升序去重后的结果
+func ParseNotifyHours(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	var hours []int
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		h, err := strconv.Atoi(p)
+		if err != nil || h < 0 || h > 23 {
+			return nil, fmt.Errorf("无效的通知时段: %s", p)
+		}
+		if !seen[h] {
+			seen[h] = true
+			hours = append(hours, h)
+		}
+	}
+	if len(hours) == 0 {
+		return nil, errors.New("至少需要一个通知时段")
+	}
+	sort.Ints(hours)
+	return hours, nil
+}
+
+// NotifyHoursSchedule 将旧版通知时段换算为 cron 表达式，如 "9,21" → "0 9,21 * * *"
+func NotifyHoursSchedule(s string) (string, error) {
+	hours, err := ParseNotifyHours(s)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(hours))
+	for i, h := range hours {
+		parts[i] = strconv.Itoa(h)
+	}
+	return "0 " + strings.Join(parts, ",") + " * * *", nil
+}
+
+// EffectiveNotifySchedule 根据设置确定实际使用的通知计划表达式：
+// 优先使用 notify_schedule，其次换算旧版 notify_hours，均无效时使用 DefaultNotifySchedule
+func EffectiveNotifySchedule(schedule, legacyHours string) string {
+	if _, err := ParseNotifySchedule(schedule); err == nil {
+		return strings.TrimSpace(schedule)
+	}
+	if expr, err := NotifyHoursSchedule(legacyHours); err == nil {
+		return expr
+	}
+	return DefaultNotifySchedule
+}
+
+// QuietHours 免打扰时段，按分钟表示，Start 大于 End 时表示跨午夜
+type QuietHours struct {
+	Start int
+	End   int
+}
+
+// ParseQuietHours 解析免打扰时段，格式为 HH:MM-HH:MM，空字符串返回 nil
+func ParseQuietHours(s string) (*QuietHours, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	start, end, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("免打扰时段格式应为 HH:MM-HH:MM: %s", s)
+	}
+	q := &QuietHours{}
+	var err error
+	if q.Start, err = parseClock(start); err != nil {
+		return nil, err
+	}
+	if q.End, err = parseClock(end); err != nil {
+		return nil, err
+	}
+	if q.Start == q.End {
+		return nil, errors.New("免打扰时段的开始与结束时间不能相同")
+	}
+	return q, nil
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间: %s", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// String 格式化为 HH:MM-HH:MM
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+// Contains 判断 t（按其所属时区）是否处于免打扰时段
+func (q *QuietHours) Contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+// Defer 免打扰时段内的时间推迟到时段结束，其余时间保持不变
+func (q *QuietHours) Defer(t time.Time) time.Time {
+	if q == nil || !q.Contains(t) {
+		return t
+	}
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.End/60, q.End%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// NotifyDueBetween 判断 loc 时区下今天是否有通知时间落在 (since, now] 内
+// 免打扰时段内的计划时间推迟到时段结束（可能来自前一天的计划）；提醒按“今天”判断，往日错过的通知不再补发
+func NotifyDueBetween(schedule cron.Schedule, quiet *QuietHours, since, now time.Time, loc *time.Location) bool {
+	local := now.In(loc)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	lower := since
+	if lower.Before(dayStart) {
+		lower = dayStart.Add(-time.Nanosecond)
+	}
+
+	from := dayStart
+	if quiet != nil {
+		from = dayStart.AddDate(0, 0, -1)
+	}
+	fire := schedule.Next(from.Add(-time.Second))
+	for i := 0; i < maxScheduleSteps && !fire.IsZero() && !fire.After(now); i++ {
+		if at := quiet.Defer(fire); at.After(lower) && !at.After(now) {
+			return true
+		}
+		fire = schedule.Next(fire)
+	}
+	return false
+}
+
+// NextNotifyTimes 预览 from 之后的 n 次通知时间，免打扰时段内的计划时间推迟到时段结束，推迟后重复的时间只保留一次
+func NextNotifyTimes(schedule cron.Schedule, quiet *QuietHours, from time.Time, n int) []time.Time {
+	times := []time.Time{}
+	fire := schedule.Next(from)
+	for i := 0; i < maxScheduleSteps && len(times) < n && !fire.IsZero(); i++ {
+		at := quiet.Defer(fire)
+		if len(times) == 0 || !times[len(times)-1].Equal(at) {
+			times = append(times, at)
+		}
+		fire = schedule.Next(fire)
+	}
+	return times
+}
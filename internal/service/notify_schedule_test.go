@@ -0,0 +1,136 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestParseNotifyHours(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"9", []int{9}, false},
+		{"21, 9,9", []int{9, 21}, false},
+		{"0", []int{0}, false},
+		{"24", nil, true},
+		{"8,x", nil, true},
+		{"", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNotifyHours(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseNotifyHours(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseNotifyHours(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEffectiveNotifySchedule(t *testing.T) {
+	tests := []struct {
+		schedule, hours, want string
+	}{
+		{"30 8 * * 1-5", "9", "30 8 * * 1-5"},
+		{"", "9,21", "0 9,21 * * *"},
+		{"", "0", "0 0 * * *"},
+		{"invalid", "x", DefaultNotifySchedule},
+		{"CRON_TZ=UTC 0 9 * * *", "", DefaultNotifySchedule},
+	}
+
+	for _, tt := range tests {
+		if got := EffectiveNotifySchedule(tt.schedule, tt.hours); got != tt.want {
+			t.Errorf("EffectiveNotifySchedule(%q, %q) = %q, want %q", tt.schedule, tt.hours, got, tt.want)
+		}
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("22:00-07:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.String() != "22:00-07:30" {
+		t.Errorf("String() = %s", q.String())
+	}
+
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 20, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		in, want time.Time
+	}{
+		{at(21, 59), at(21, 59)},
+		{at(22, 0), at(7, 30).AddDate(0, 0, 1)},
+		{at(3, 0), at(7, 30)},
+		{at(7, 30), at(7, 30)},
+	}
+	for _, tt := range tests {
+		if got := q.Defer(tt.in); !got.Equal(tt.want) {
+			t.Errorf("Defer(%s) = %s, want %s", tt.in.Format("15:04"), got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"22:00", "25:00-07:00", "08:00-08:00"} {
+		if _, err := ParseQuietHours(s); err == nil {
+			t.Errorf("ParseQuietHours(%q) 应返回错误", s)
+		}
+	}
+	if q, err := ParseQuietHours(""); q != nil || err != nil {
+		t.Errorf("ParseQuietHours(\"\") = %v, %v", q, err)
+	}
+}
+
+func TestNotifyDueBetween(t *testing.T) {
+	weekdays, _ := ParseNotifySchedule("30 8 * * 1-5")
+	daily, _ := ParseNotifySchedule("0 23 * * *")
+	quiet, _ := ParseQuietHours("22:00-07:00")
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+
+	// 2026-03-20 为周五，2026-03-21 为周六
+	friday := time.Date(2026, 3, 20, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		sched cron.Schedule
+		quiet *QuietHours
+		since time.Time
+		now   time.Time
+		loc   *time.Location
+		want  bool
+	}{
+		{"工作日计划时间", weekdays, nil, friday.Add(-time.Minute), friday, time.UTC, true},
+		{"已执行过", weekdays, nil, friday, friday.Add(time.Minute), time.UTC, false},
+		{"周末不通知", weekdays, nil, friday.AddDate(0, 0, 1).Add(-time.Minute), friday.AddDate(0, 0, 1), time.UTC, false},
+		{"停机后补发今天的通知", weekdays, nil, friday.AddDate(0, 0, -3), friday.Add(2 * time.Hour), time.UTC, true},
+		{"按时区计算", weekdays, nil, friday.Add(-8*time.Hour - time.Minute), friday.Add(-8 * time.Hour), shanghai, true},
+		{"免打扰时段内不通知", daily, quiet, time.Date(2026, 3, 20, 22, 59, 0, 0, time.UTC), time.Date(2026, 3, 20, 23, 0, 0, 0, time.UTC), time.UTC, false},
+		{"免打扰结束后补发前一天的计划", daily, quiet, time.Date(2026, 3, 21, 6, 59, 0, 0, time.UTC), time.Date(2026, 3, 21, 7, 0, 0, 0, time.UTC), time.UTC, true},
+	}
+
+	for _, tt := range tests {
+		if got := NotifyDueBetween(tt.sched, tt.quiet, tt.since, tt.now, tt.loc); got != tt.want {
+			t.Errorf("%s: NotifyDueBetween() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextNotifyTimes(t *testing.T) {
+	schedule, _ := ParseNotifySchedule("0 6,9,23 * * *")
+	quiet, _ := ParseQuietHours("22:00-07:00")
+	from := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+
+	got := NextNotifyTimes(schedule, quiet, from, 3)
+	want := []time.Time{
+		time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 21, 7, 0, 0, 0, time.UTC), // 23 点与次日 6 点均推迟到 7 点，只保留一次
+		time.Date(2026, 3, 21, 9, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NextNotifyTimes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+// 汇率数据源类型
+const (
+	RateProviderECB  = "ecb"
+	RateProviderJSON = "json"
+)
+
+// DefaultECBURL 欧洲央行每日参考汇率（如需历史数据可改用 eurofxref-hist-90d.xml）
+const DefaultECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// ErrRateNotFound 找不到可用汇率
+var ErrRateNotFound = errors.New("未找到可用汇率")
+
+// RateSnapshot 某一天的一组汇率，1 单位 Base 可兑换 Rates[currency] 单位对应币种
+type RateSnapshot struct {
+	Base  string
+	Date  time.Time
+	Rates map[string]float64
+}
+
+// RateProvider 汇率数据源
+type RateProvider interface {
+	// Name 数据源名称，写入汇率记录的 Source 字段
+	Name() string
+	// Fetch 拉取汇率，可能包含多天的数据
+	Fetch(ctx context.Context) ([]RateSnapshot, error)
+}
+
+// NewRateProvider 根据类型和地址创建汇率数据源
+func NewRateProvider(kind, sourceURL string) (RateProvider, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	switch kind {
+	case RateProviderECB:
+		if sourceURL == "" {
+			sourceURL = DefaultECBURL
+		}
+		return &ECBProvider{URL: sourceURL, client: client}, nil
+	case RateProviderJSON:
+		if sourceURL == "" {
+			return nil, errors.New("JSON 汇率源需要配置地址")
+		}
+		return &JSONRateProvider{URL: sourceURL, client: client}, nil
+	default:
+		return nil, fmt.Errorf("不支持的汇率源类型: %s", kind)
+	}
+}
+
+// ECBProvider 欧洲央行 XML 格式汇率源（基准币种为 EUR）
+type ECBProvider struct {
+	URL    string
+	client *http.Client
+}
+
+// ecbEnvelope ECB eurofxref XML 结构，外层 Cube 下按日期分组
+type ecbEnvelope struct {
+	Cube struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string  `xml:"currency,attr"`
+				Rate     float64 `xml:"rate,attr"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	} `xml:"Cube"`
+}
+
+// Name 数据源名称
+func (p *ECBProvider) Name() string {
+	return RateProviderECB
+}
+
+// Fetch 拉取并解析 ECB XML
+func (p *ECBProvider) Fetch(ctx context.Context) ([]RateSnapshot, error) {
+	resp, err := getWithContext(ctx, p.client, p.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("解析 ECB 汇率失败: %w", err)
+	}
+
+	var snapshots []RateSnapshot
+	for _, day := range envelope.Cube.Days {
+		date, err := time.Parse("2006-01-02", day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("解析 ECB 汇率日期失败: %w", err)
+		}
+		rates := make(map[string]float64, len(day.Rates))
+		for _, r := range day.Rates {
+			if r.Currency != "" && r.Rate > 0 {
+				rates[strings.ToUpper(r.Currency)] = r.Rate
+			}
+		}
+		snapshots = append(snapshots, RateSnapshot{Base: "EUR", Date: date, Rates: rates})
+	}
+	if len(snapshots) == 0 {
+		return nil, errors.New("ECB 汇率数据为空")
+	}
+	return snapshots, nil
+}
+
+// JSONRateProvider 通用 JSON 汇率源
+// 响应格式：{"base": "USD", "date": "2024-01-31", "rates": {"CNY": 7.1, ...}}
+// 未提供 date 时使用 timestamp（Unix 秒，按 UTC 取日期），两者都没有则取全局时区下的今天
+type JSONRateProvider struct {
+	URL    string
+	client *http.Client
+}
+
+type jsonRateResponse struct {
+	Base      string             `json:"base"`
+	Date      string             `json:"date"`
+	Timestamp int64              `json:"timestamp"`
+	Rates     map[string]float64 `json:"rates"`
+}
+
+// Name 数据源名称
+func (p *JSONRateProvider) Name() string {
+	return RateProviderJSON
+}
+
+// Fetch 拉取并解析 JSON 汇率
+func (p *JSONRateProvider) Fetch(ctx context.Context) ([]RateSnapshot, error) {
+	resp, err := getWithContext(ctx, p.client, p.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data jsonRateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析 JSON 汇率失败: %w", err)
+	}
+	if data.Base == "" || len(data.Rates) == 0 {
+		return nil, errors.New("JSON 汇率缺少 base 或 rates")
+	}
+
+	var date time.Time
+	switch {
+	case data.Date != "":
+		date, err = time.Parse("2006-01-02", data.Date)
+		if err != nil {
+			return nil, fmt.Errorf("解析 JSON 汇率日期失败: %w", err)
+		}
+	case data.Timestamp > 0:
+		date = model.CalendarDate(time.Unix(data.Timestamp, 0).UTC())
+	default:
+		date = model.Today(model.GlobalLocation())
+	}
+
+	rates := make(map[string]float64, len(data.Rates))
+	for currency, rate := range data.Rates {
+		if rate > 0 {
+			rates[strings.ToUpper(currency)] = rate
+		}
+	}
+	return []RateSnapshot{{Base: strings.ToUpper(data.Base), Date: date, Rates: rates}}, nil
+}
+
+// RefreshRates 从数据源拉取汇率并保存，同一基准/币种/日期的记录会被覆盖
+func RefreshRates(ctx context.Context, provider RateProvider) (int, error) {
+	snapshots, err := provider.Fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := clock.Now()
+	var records []model.ExchangeRate
+	for _, snap := range snapshots {
+		for currency, rate := range snap.Rates {
+			records = append(records, model.ExchangeRate{
+				Base:      snap.Base,
+				Currency:  currency,
+				Rate:      rate,
+				RateDate:  snap.Date,
+				Source:    provider.Name(),
+				FetchedAt: now,
+			})
+		}
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	err = model.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "currency"}, {Name: "rate_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "fetched_at"}),
+	}).CreateInBatches(records, 200).Error
+	if err != nil {
+		return 0, fmt.Errorf("保存汇率失败: %w", err)
+	}
+	return len(records), nil
+}
+
+// ConvertAmount 按指定日期的汇率换算金额
+// 优先使用该日期当天或之前最近的汇率，早于所有记录时使用最早的汇率
+func ConvertAmount(amount float64, from, to string, at time.Time) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+
+	var bases []string
+	if err := model.GetDB().Model(&model.ExchangeRate{}).Distinct("base").Pluck("base", &bases).Error; err != nil {
+		return 0, err
+	}
+
+	for _, base := range bases {
+		fromRate, ok := lookupRate(base, from, at)
+		if !ok {
+			continue
+		}
+		toRate, ok := lookupRate(base, to, at)
+		if !ok {
+			continue
+		}
+		return amount / fromRate * toRate, nil
+	}
+	return 0, fmt.Errorf("%w: %s -> %s", ErrRateNotFound, from, to)
+}
+
+// LatestRates 获取每个币种最新的一条汇率记录
+func LatestRates() ([]model.ExchangeRate, error) {
+	var rates []model.ExchangeRate
+	err := model.GetDB().
+		Where(`rate_date = (SELECT MAX(r.rate_date) FROM exchange_rates r
+			WHERE r.base = exchange_rates.base AND r.currency = exchange_rates.currency)`).
+		Order("base asc, currency asc").
+		Find(&rates).Error
+	return rates, err
+}
+
+// lookupRate 查找某基准下某币种在指定日期的汇率
+func lookupRate(base, currency string, at time.Time) (float64, bool) {
+	if base == currency {
+		return 1, true
+	}
+
+	var rate model.ExchangeRate
+	result := model.GetDB().
+		Where("base = ? AND currency = ? AND rate_date <= ?", base, currency, at).
+		Order("rate_date desc").
+		Limit(1).Find(&rate)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result = model.GetDB().
+			Where("base = ? AND currency = ?", base, currency).
+			Order("rate_date asc").
+			Limit(1).Find(&rate)
+	}
+	if result.Error != nil || result.RowsAffected == 0 || rate.Rate <= 0 {
+		return 0, false
+	}
+	return rate.Rate, true
+}
+
+// getWithContext 发送 GET 请求并检查状态码
+func getWithContext(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("汇率源返回错误: %d", resp.StatusCode)
+	}
+	return resp, nil
+}
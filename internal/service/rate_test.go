@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+const ecbTwoDays = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2026-03-02">
+			<Cube currency="USD" rate="1.1"/>
+			<Cube currency="CNY" rate="7.7"/>
+		</Cube>
+		<Cube time="2026-03-16">
+			<Cube currency="USD" rate="1.0"/>
+			<Cube currency="CNY" rate="8.0"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+const ecbUpdatedDay = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<Cube>
+		<Cube time="2026-03-16">
+			<Cube currency="USD" rate="1.0"/>
+			<Cube currency="CNY" rate="7.9"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+// serveRates 启动返回 body 的本地汇率源，body 可在测试中修改
+func serveRates(t *testing.T, body *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(*body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// resetRates 清空汇率记录
+func resetRates(t *testing.T) {
+	t.Helper()
+	db := model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true})
+	if err := db.Delete(&model.ExchangeRate{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Delete(&model.ExchangeRate{}) })
+}
+
+func TestECBProviderFetch(t *testing.T) {
+	body := ecbTwoDays
+	provider, err := NewRateProvider(RateProviderECB, serveRates(t, &body).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := provider.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("len(snapshots) = %d, want 2", len(snapshots))
+	}
+	first := snapshots[0]
+	if first.Base != "EUR" || !first.Date.Equal(date("2026-03-02")) || first.Rates["USD"] != 1.1 || first.Rates["CNY"] != 7.7 {
+		t.Errorf("snapshots[0] = %+v", first)
+	}
+	if !snapshots[1].Date.Equal(date("2026-03-16")) || snapshots[1].Rates["CNY"] != 8.0 {
+		t.Errorf("snapshots[1] = %+v", snapshots[1])
+	}
+
+	body = `<gesmes:Envelope></gesmes:Envelope>`
+	if _, err := provider.Fetch(context.Background()); err == nil {
+		t.Error("空的 ECB 数据应返回错误")
+	}
+}
+
+func TestJSONRateProviderFetch(t *testing.T) {
+	// 未提供日期时按全局时区取今天：UTC 3 月 20 日 20:00 即上海 3 月 21 日
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 20, 0, 0, 0, time.UTC))))
+	if err := SetSetting(model.SettingTimezone, "Asia/Shanghai"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetSetting(model.SettingTimezone, "") })
+
+	tests := []struct {
+		name     string
+		body     string
+		wantDate string
+		wantErr  bool
+	}{
+		{"日期", `{"base": "usd", "date": "2026-03-10", "rates": {"cny": 7.2, "EUR": 0.9, "BAD": 0}}`, "2026-03-10", false},
+		{"时间戳", `{"base": "USD", "timestamp": 1773964800, "rates": {"CNY": 7.2}}`, "2026-03-20", false},
+		{"全局时区的今天", `{"base": "USD", "rates": {"CNY": 7.2}}`, "2026-03-21", false},
+		{"缺少 rates", `{"base": "USD"}`, "", true},
+		{"无效日期", `{"base": "USD", "date": "03/10/2026", "rates": {"CNY": 7.2}}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			provider, err := NewRateProvider(RateProviderJSON, serveRates(t, &body).URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snapshots, err := provider.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			snap := snapshots[0]
+			if snap.Base != "USD" || !snap.Date.Equal(date(tt.wantDate)) || snap.Rates["CNY"] != 7.2 {
+				t.Errorf("snapshot = %+v, want USD %s", snap, tt.wantDate)
+			}
+			if _, ok := snap.Rates["BAD"]; ok {
+				t.Error("非正数汇率应被忽略")
+			}
+		})
+	}
+
+	if _, err := NewRateProvider(RateProviderJSON, ""); err == nil {
+		t.Error("JSON 汇率源未配置地址时应返回错误")
+	}
+}
+
+func TestRefreshRatesUpsertsAndKeepsHistory(t *testing.T) {
+	resetRates(t)
+	body := ecbTwoDays
+	provider, err := NewRateProvider(RateProviderECB, serveRates(t, &body).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := RefreshRates(context.Background(), provider); err != nil || n != 4 {
+		t.Fatalf("RefreshRates() = %d, %v, want 4", n, err)
+	}
+
+	// 再次刷新只返回最新一天：覆盖当天的汇率，保留更早日期的记录
+	body = ecbUpdatedDay
+	if n, err := RefreshRates(context.Background(), provider); err != nil || n != 2 {
+		t.Fatalf("RefreshRates() = %d, %v, want 2", n, err)
+	}
+	var count int64
+	model.GetDB().Model(&model.ExchangeRate{}).Count(&count)
+	if count != 4 {
+		t.Errorf("汇率记录数 = %d, want 4", count)
+	}
+	latest, err := LatestRates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range latest {
+		if r.Currency == "CNY" && (r.Rate != 7.9 || !r.RateDate.Equal(date("2026-03-16"))) {
+			t.Errorf("最新 CNY 汇率 = %+v, want 7.9 on 2026-03-16", r)
+		}
+	}
+	var old model.ExchangeRate
+	if err := model.GetDB().Where("currency = ? AND rate_date = ?", "CNY", date("2026-03-02")).First(&old).Error; err != nil || old.Rate != 7.7 {
+		t.Errorf("较早日期的汇率 = %+v, %v, want 7.7", old, err)
+	}
+}
+
+func TestConvertAmountUsesPaymentDateRate(t *testing.T) {
+	resetRates(t)
+	body := ecbTwoDays
+	provider, err := NewRateProvider(RateProviderECB, serveRates(t, &body).URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RefreshRates(context.Background(), provider); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		at       string
+		want     float64
+	}{
+		{"USD", "CNY", "2026-03-10", 70},   // 3 月 2 日汇率：10 / 1.1 * 7.7
+		{"USD", "CNY", "2026-03-16", 80},   // 当天汇率
+		{"USD", "CNY", "2026-03-31", 80},   // 之后最近一天的汇率
+		{"USD", "CNY", "2026-01-01", 70},   // 早于所有记录时使用最早的汇率
+		{"CNY", "EUR", "2026-03-20", 1.25}, // 10 / 8
+		{"cny", "CNY", "2026-03-20", 10},
+	}
+	for _, tt := range tests {
+		got, err := ConvertAmount(10, tt.from, tt.to, date(tt.at))
+		if err != nil {
+			t.Errorf("ConvertAmount(%s->%s, %s) error = %v", tt.from, tt.to, tt.at, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ConvertAmount(%s->%s, %s) = %v, want %v", tt.from, tt.to, tt.at, got, tt.want)
+		}
+	}
+
+	if _, err := ConvertAmount(10, "USD", "JPY", date("2026-03-20")); err == nil {
+		t.Error("缺少汇率时应返回错误")
+	}
+}
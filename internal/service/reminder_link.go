@@ -0,0 +1,78 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"subdock/internal/config"
+)
+
+// 提醒链接支持的操作
+const (
+	ReminderActionAck    = "ack"    // 已知晓本周期提醒
+	ReminderActionSnooze = "snooze" // 暂停提醒若干天
+)
+
+// ReminderLinkTTL 提醒链接有效期
+const ReminderLinkTTL = 14 * 24 * time.Hour
+
+// ErrInvalidReminderLink 链接签名无效或已过期
+var ErrInvalidReminderLink = errors.New("链接无效或已过期")
+
+// ReminderAction 提醒链接携带的操作
+type ReminderAction struct {
+	SubscriptionID uint
+	Action         string
+	Days           int // 仅 snooze 使用
+	Expires        int64
+}
+
+// Sign 计算操作签名，密钥由 JWT 密钥派生
+func (a ReminderAction) Sign() string {
+	mac := hmac.New(sha256.New, []byte("reminder-link:"+config.Get().JWTSecret))
+	fmt.Fprintf(mac, "%d|%s|%d|%d", a.SubscriptionID, a.Action, a.Days, a.Expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名与有效期
+func (a ReminderAction) Verify(sig string, now time.Time) error {
+	if a.Action != ReminderActionAck && a.Action != ReminderActionSnooze {
+		return ErrInvalidReminderLink
+	}
+	if now.Unix() > a.Expires {
+		return ErrInvalidReminderLink
+	}
+	if !hmac.Equal([]byte(a.Sign()), []byte(sig)) {
+		return ErrInvalidReminderLink
+	}
+	return nil
+}
+
+// URL 生成带签名的链接，baseURL 为站点对外访问地址
+func (a ReminderAction) URL(baseURL string) string {
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(a.Expires, 10))
+	if a.Action == ReminderActionSnooze {
+		q.Set("days", strconv.Itoa(a.Days))
+	}
+	q.Set("sig", a.Sign())
+	return fmt.Sprintf("%s/api/reminders/%d/%s?%s", strings.TrimRight(baseURL, "/"), a.SubscriptionID, a.Action, q.Encode())
+}
+
+// ReminderLinksText 提醒消息末尾附带的一键操作链接，未配置站点地址时返回空字符串
+func ReminderLinksText(baseURL string, subscriptionID uint, now time.Time) string {
+	if baseURL == "" {
+		return ""
+	}
+	expires := now.Add(ReminderLinkTTL).Unix()
+	ack := ReminderAction{SubscriptionID: subscriptionID, Action: ReminderActionAck, Expires: expires}
+	snooze := ReminderAction{SubscriptionID: subscriptionID, Action: ReminderActionSnooze, Days: 1, Expires: expires}
+	return "\n\n我已知晓: " + ack.URL(baseURL) + "\n暂停 1 天: " + snooze.URL(baseURL)
+}
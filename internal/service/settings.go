@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/model"
+)
+
+// 通知渠道、汇率、备份等通用设置项，其余设置项定义在对应功能旁
+const (
+	SettingTelegramBotToken       = "telegram_bot_token"
+	SettingTelegramChatID         = "telegram_chat_id"
+	SettingTelegramAPIURL         = "telegram_api_url"
+	SettingTelegramBotEnabled     = "telegram_bot_enabled"
+	SettingTelegramAllowedChatIDs = "telegram_allowed_chat_ids"
+	SettingBarkURL                = "bark_url"
+	SettingPublicURL              = "public_url"
+	SettingRateProvider           = "rate_provider"
+	SettingRateSourceURL          = "rate_source_url"
+	SettingBaseCurrency           = "base_currency"
+	SettingBackupEnabled          = "backup_enabled"
+	SettingBackupKeepLast         = "backup_keep_last"
+	SettingBackupKeepDaily        = "backup_keep_daily"
+	SettingBackupKeepWeekly       = "backup_keep_weekly"
+)
+
+// maxBackupKeep 快照保留数量上限
+const maxBackupKeep = 1000
+
+// settingDef 设置项定义：未设置时的默认值，以及校验并规范化输入的函数（为空表示任意文本）
+type settingDef struct {
+	def       string
+	normalize func(string) (string, error)
+}
+
+// settingDefs 所有可通过设置接口修改的设置项
+var settingDefs = map[string]settingDef{
+	SettingNotifyHours:    {"9", normalizeNotifyHours},
+	SettingNotifySchedule: {"", normalizeSchedule},
+	SettingQuietHours:     {"", normalizeQuietHours},
+
+	SettingTelegramBotToken:       {"", nil},
+	SettingTelegramChatID:         {"", nil},
+	SettingTelegramAPIURL:         {DefaultTelegramAPIURL, normalizeURL},
+	SettingTelegramBotEnabled:     {"false", normalizeBool},
+	SettingTelegramAllowedChatIDs: {"", normalizeChatIDs},
+	SettingBarkURL:                {"", normalizeURL},
+	SettingPublicURL:              {"", normalizeURL},
+
+	SettingRateProvider:  {"", oneOf(RateProviderECB, RateProviderJSON)},
+	SettingRateSourceURL: {"", normalizeURL},
+	SettingBaseCurrency:  {"CNY", normalizeCurrency},
+
+	SettingBackupEnabled:    {"true", normalizeBool},
+	SettingBackupKeepLast:   {"7", intRange(0, maxBackupKeep)},
+	SettingBackupKeepDaily:  {"7", intRange(0, maxBackupKeep)},
+	SettingBackupKeepWeekly: {"4", intRange(0, maxBackupKeep)},
+
+	model.SettingRemindOffsets:   {model.DefaultRemindOffsets, model.NormalizeRemindOffsets},
+	model.SettingTimezone:        {"", normalizeTimezone},
+	model.SettingAutoRenewPolicy: {model.AutoRenewBackfill, oneOf(model.AutoRenewBackfill, model.AutoRenewRebase)},
+
+	SettingNotifyMode:     {NotifyModeIndividual, oneOf(NotifyModeIndividual, NotifyModeDigest)},
+	SettingDigestCadence:  {DigestDaily, oneOf(DigestDaily, DigestWeekly)},
+	SettingDigestSchedule: {"", normalizeSchedule},
+	SettingDigestDays:     {strconv.Itoa(DefaultDigestDays), normalizeDigestDays},
+
+	model.SettingOverdueInterval:               {strconv.Itoa(model.DefaultOverdueInterval), normalizeOverdue},
+	model.SettingOverdueMax:                    {strconv.Itoa(model.DefaultOverdueMax), normalizeOverdue},
+	model.SettingOverdueEscalateAfter:          {strconv.Itoa(model.DefaultOverdueEscalateAfter), normalizeOverdue},
+	model.SettingOverdueEscalateTelegramChatID: {"", nil},
+	model.SettingOverdueEscalateBarkURL:        {"", normalizeURL},
+}
+
+// SettingErrors 按设置项返回的校验错误
+type SettingErrors map[string]string
+
+// Error 实现 error 接口，按设置项名称排序拼接
+func (e SettingErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + ": " + e[key]
+	}
+	return "设置校验失败: " + strings.Join(parts, "; ")
+}
+
+// GetSetting 读取设置值，未设置时返回该设置项的默认值
+func GetSetting(key string) string {
+	if v, ok := model.LookupSetting(key); ok {
+		return v
+	}
+	return settingDefs[key].def
+}
+
+// GetSettingBool 读取布尔类型的设置值
+func GetSettingBool(key string) bool {
+	return GetSetting(key) == "true"
+}
+
+// GetSettingInt 读取整数类型的设置值，无法解析时返回默认值
+func GetSettingInt(key string) int {
+	if v, err := strconv.Atoi(GetSetting(key)); err == nil {
+		return v
+	}
+	v, _ := strconv.Atoi(settingDefs[key].def)
+	return v
+}
+
+// SetSetting 校验并保存单个设置项，空字符串表示清除
+func SetSetting(key, value string) error {
+	_, err := UpdateSettings(map[string]*string{key: &value})
+	return err
+}
+
+// UpdateSettings 校验并在一个事务中保存多个设置项，返回保存后的值
+// 值为 null 或空字符串时清除该设置项，之后读取时使用默认值；任一项校验失败时不保存，返回 SettingErrors
+// 旧版 notify_hours 只按原样保存，未设置 notify_schedule 时由 EffectiveNotifySchedule 换算，不会覆盖已有的通知计划
+func UpdateSettings(changes map[string]*string) (map[string]string, error) {
+	values := make(map[string]string, len(changes))
+	errs := SettingErrors{}
+	for key, v := range changes {
+		def, ok := settingDefs[key]
+		if !ok {
+			errs[key] = "未知的设置项"
+			continue
+		}
+		value := ""
+		if v != nil {
+			value = strings.TrimSpace(*v)
+		}
+		if value != "" && def.normalize != nil {
+			normalized, err := def.normalize(value)
+			if err != nil {
+				errs[key] = err.Error()
+				continue
+			}
+			value = normalized
+		}
+		values[key] = value
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	// 早期版本保存 notify_hours 时会写入换算出的通知计划，这类计划随旧版通知时段一起更新
+	if _, ok := changes[SettingNotifySchedule]; !ok {
+		if _, ok := changes[SettingNotifyHours]; ok {
+			derived, err := NotifyHoursSchedule(GetSetting(SettingNotifyHours))
+			if schedule := GetSetting(SettingNotifySchedule); err == nil && schedule == derived {
+				values[SettingNotifySchedule] = ""
+			}
+		}
+	}
+	err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		for key, value := range values {
+			if err := tx.Where("key = ?", key).Delete(&model.Setting{}).Error; err != nil {
+				return err
+			}
+			if value == "" {
+				continue
+			}
+			if err := tx.Create(&model.Setting{Key: key, Value: value}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	model.InvalidateSettings()
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		if value == "" {
+			values[key] = settingDefs[key].def
+		}
+	}
+	return values, nil
+}
+
+// oneOf 校验取值为给定选项之一
+func oneOf(options ...string) func(string) (string, error) {
+	return func(s string) (string, error) {
+		for _, o := range options {
+			if s == o {
+				return s, nil
+			}
+		}
+		return "", fmt.Errorf("取值应为 %s 之一", strings.Join(options, "、"))
+	}
+}
+
+// intRange 校验取值为 [min, max] 范围内的整数
+func intRange(min, max int) func(string) (string, error) {
+	return func(s string) (string, error) {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < min || v > max {
+			return "", fmt.Errorf("应为 %d-%d 的整数", min, max)
+		}
+		return strconv.Itoa(v), nil
+	}
+}
+
+func normalizeBool(s string) (string, error) {
+	return oneOf("true", "false")(s)
+}
+
+// normalizeURL 校验 http/https 地址并去掉末尾的 /
+func normalizeURL(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("应为 http 或 https 地址")
+	}
+	return strings.TrimRight(s, "/"), nil
+}
+
+func normalizeCurrency(s string) (string, error) {
+	s = strings.ToUpper(s)
+	if len(s) != 3 || strings.Trim(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", errors.New("应为 3 位字母币种代码")
+	}
+	return s, nil
+}
+
+// normalizeChatIDs 校验逗号分隔的 Telegram 会话 ID
+func normalizeChatIDs(s string) (string, error) {
+	var ids []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(p, 10, 64); err != nil {
+			return "", fmt.Errorf("无效的会话 ID: %s", p)
+		}
+		ids = append(ids, p)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+func normalizeNotifyHours(s string) (string, error) {
+	hours, err := ParseNotifyHours(s)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(hours))
+	for i, h := range hours {
+		parts[i] = strconv.Itoa(h)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func normalizeSchedule(s string) (string, error) {
+	if _, err := ParseNotifySchedule(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func normalizeQuietHours(s string) (string, error) {
+	quiet, err := ParseQuietHours(s)
+	if err != nil {
+		return "", err
+	}
+	return quiet.String(), nil
+}
+
+func normalizeTimezone(s string) (string, error) {
+	if err := model.ValidateTimezone(s); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func normalizeDigestDays(s string) (string, error) {
+	days, err := ParseDigestDays(s)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(days), nil
+}
+
+func normalizeOverdue(s string) (string, error) {
+	v, ok := model.ParseOverdueSetting(s)
+	if !ok {
+		return "", errors.New("应为 0-365 的整数")
+	}
+	return strconv.Itoa(v), nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"subdock/internal/model"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestUpdateSettingsValidation(t *testing.T) {
+	_, err := UpdateSettings(map[string]*string{
+		SettingBarkURL:        ptr("not a url"),
+		SettingNotifyHours:    ptr("25"),
+		SettingBackupKeepLast: ptr("-1"),
+		SettingBaseCurrency:   ptr("usd"),
+		"unknown":             ptr("x"),
+	})
+	var errs SettingErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("UpdateSettings() error = %v, want SettingErrors", err)
+	}
+	for _, key := range []string{SettingBarkURL, SettingNotifyHours, SettingBackupKeepLast, "unknown"} {
+		if errs[key] == "" {
+			t.Errorf("缺少 %s 的校验错误: %v", key, errs)
+		}
+	}
+	if _, ok := errs[SettingBaseCurrency]; ok {
+		t.Errorf("合法的币种不应报错: %v", errs)
+	}
+	// 任一项校验失败时不保存
+	if got := GetSetting(SettingBaseCurrency); got != "CNY" {
+		t.Errorf("base_currency = %q, want CNY", got)
+	}
+}
+
+func TestUpdateSettingsNormalizeAndClear(t *testing.T) {
+	t.Cleanup(func() {
+		UpdateSettings(map[string]*string{SettingBarkURL: nil, SettingNotifyHours: nil, SettingNotifySchedule: nil, SettingBaseCurrency: nil})
+	})
+
+	values, err := UpdateSettings(map[string]*string{
+		SettingBarkURL:      ptr(" https://api.day.app/key/ "),
+		SettingNotifyHours:  ptr("21, 9"),
+		SettingBaseCurrency: ptr("usd"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		SettingBarkURL:      "https://api.day.app/key",
+		SettingNotifyHours:  "9,21",
+		SettingBaseCurrency: "USD",
+	}
+	for key, v := range want {
+		if values[key] != v || GetSetting(key) != v {
+			t.Errorf("%s = %q / %q, want %q", key, values[key], GetSetting(key), v)
+		}
+	}
+
+	if got := EffectiveNotifySchedule(GetSetting(SettingNotifySchedule), GetSetting(SettingNotifyHours)); got != "0 9,21 * * *" {
+		t.Errorf("未设置通知计划时应按旧版通知时段换算，got %q", got)
+	}
+
+	// 已设置通知计划时，再次保存旧版通知时段不覆盖通知计划
+	if _, err := UpdateSettings(map[string]*string{SettingNotifySchedule: ptr("30 8 * * 1-5")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateSettings(map[string]*string{SettingNotifyHours: ptr("9")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := EffectiveNotifySchedule(GetSetting(SettingNotifySchedule), GetSetting(SettingNotifyHours)); got != "30 8 * * 1-5" {
+		t.Errorf("notify_schedule = %q, want 30 8 * * 1-5", got)
+	}
+
+	// 与旧版通知时段换算结果一致的通知计划随通知时段更新
+	if _, err := UpdateSettings(map[string]*string{SettingNotifySchedule: ptr("0 9 * * *")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateSettings(map[string]*string{SettingNotifyHours: ptr("7")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := EffectiveNotifySchedule(GetSetting(SettingNotifySchedule), GetSetting(SettingNotifyHours)); got != "0 7 * * *" {
+		t.Errorf("notify_schedule = %q, want 0 7 * * *", got)
+	}
+
+	// 空字符串与 null 均清除设置，之后读取默认值
+	if _, err := UpdateSettings(map[string]*string{SettingBarkURL: ptr(""), SettingBaseCurrency: nil}); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetSetting(SettingBarkURL); got != "" {
+		t.Errorf("bark_url = %q, want empty", got)
+	}
+	if got := GetSetting(SettingBaseCurrency); got != "CNY" {
+		t.Errorf("base_currency = %q, want CNY", got)
+	}
+	var count int64
+	model.GetDB().Model(&model.Setting{}).Where("key IN ?", []string{SettingBarkURL, SettingBaseCurrency}).Count(&count)
+	if count != 0 {
+		t.Errorf("清除后仍有 %d 条设置记录", count)
+	}
+}
+
+func TestSettingsCache(t *testing.T) {
+	t.Cleanup(func() { SetSetting(SettingPublicURL, "") })
+
+	if err := SetSetting(SettingPublicURL, "https://a.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := GetSetting(SettingPublicURL); got != "https://a.example.com" {
+		t.Fatalf("public_url = %q", got)
+	}
+
+	// 绕过设置服务直接修改数据库时，丢弃缓存前仍读取缓存值
+	model.GetDB().Model(&model.Setting{}).Where("key = ?", SettingPublicURL).Update("value", "https://b.example.com")
+	if got := GetSetting(SettingPublicURL); got != "https://a.example.com" {
+		t.Errorf("public_url = %q, want cached value", got)
+	}
+	model.InvalidateSettings()
+	if got := GetSetting(SettingPublicURL); got != "https://b.example.com" {
+		t.Errorf("public_url = %q after invalidate", got)
+	}
+}
+
+func TestModelSettingsReadThroughCache(t *testing.T) {
+	t.Cleanup(func() {
+		UpdateSettings(map[string]*string{model.SettingOverdueInterval: nil, model.SettingRemindOffsets: nil})
+	})
+
+	if _, err := UpdateSettings(map[string]*string{
+		model.SettingOverdueInterval: ptr("5"),
+		model.SettingRemindOffsets:   ptr("3,0"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := model.GlobalOverduePolicy().Interval; got != 5 {
+		t.Errorf("GlobalOverduePolicy().Interval = %d, want 5", got)
+	}
+	if got := model.FormatRemindOffsets(model.GlobalRemindOffsets()); got != "3,0" {
+		t.Errorf("GlobalRemindOffsets() = %s, want 3,0", got)
+	}
+
+	// 模型层同样读取缓存，直接修改数据库后需丢弃缓存
+	model.GetDB().Model(&model.Setting{}).Where("key = ?", model.SettingRemindOffsets).Update("value", "1")
+	if got := model.FormatRemindOffsets(model.GlobalRemindOffsets()); got != "3,0" {
+		t.Errorf("GlobalRemindOffsets() = %s, want cached 3,0", got)
+	}
+	model.InvalidateSettings()
+	if got := model.FormatRemindOffsets(model.GlobalRemindOffsets()); got != "1" {
+		t.Errorf("GlobalRemindOffsets() = %s after invalidate, want 1", got)
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/config"
+	"subdock/internal/model"
+)
+
+// snapshotTimeLayout 快照文件名中的时间格式，精确到毫秒
+const snapshotTimeLayout = "20060102-150405.000"
+
+// snapshotNamePattern 快照文件名格式，恢复和下载时只接受该格式，防止路径穿越
+var snapshotNamePattern = regexp.MustCompile(`^subdock-(\d{8}-\d{6}\.\d{3})(?:-([a-z][a-z-]*))?\.db$`)
+
+// maxSnapshotNameAttempts 快照文件名冲突时顺延毫秒重试的次数
+const maxSnapshotNameAttempts = 1000
+
+// SnapshotSourceAuto 定时任务生成的快照来源，只有该来源的快照参与轮换清理
+const SnapshotSourceAuto = "auto"
+
+// ErrSnapshotNotFound 快照不存在
+var ErrSnapshotNotFound = errors.New("快照不存在")
+
+// Snapshot 数据库快照文件
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Source    string    `json:"source"` // auto 为定时快照，其余为文件名后缀，如 manual、pre-restore
+}
+
+// SnapshotRetention 快照保留策略，三者取并集；全部为 0 时不清理
+type SnapshotRetention struct {
+	KeepLast   int `json:"keep_last"`   // 保留最近 N 个
+	KeepDaily  int `json:"keep_daily"`  // 最近 N 天每天保留最新一个
+	KeepWeekly int `json:"keep_weekly"` // 最近 N 周每周保留最新一个
+}
+
+// SnapshotDir 快照目录
+func SnapshotDir() string {
+	return filepath.Join(config.Get().DataDir, "backups")
+}
+
+// CreateSnapshot 使用 VACUUM INTO 生成一致的在线快照，suffix 用于区分手动、恢复前等来源
+func CreateSnapshot(suffix string) (*Snapshot, error) {
+	dir := SnapshotDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建快照目录失败: %w", err)
+	}
+
+	// 先独占创建空文件占用文件名，同一时刻生成的快照顺延毫秒避免冲突；VACUUM INTO 可写入空文件
+	now := clock.Now()
+	var name, path string
+	for i := 0; ; i++ {
+		name = snapshotName(now, suffix)
+		path = filepath.Join(dir, name)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if !os.IsExist(err) || i >= maxSnapshotNameAttempts {
+			return nil, fmt.Errorf("创建快照文件失败: %w", err)
+		}
+		now = now.Add(time.Millisecond)
+	}
+
+	if err := model.GetDB().Exec("VACUUM INTO ?", path).Error; err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("生成快照失败: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	createdAt, source, _ := parseSnapshotName(name)
+	return &Snapshot{Name: name, Size: info.Size(), CreatedAt: createdAt, Source: source}, nil
+}
+
+// snapshotName 生成快照文件名
+func snapshotName(t time.Time, suffix string) string {
+	name := "subdock-" + t.Format(snapshotTimeLayout)
+	if suffix != "" {
+		name += "-" + suffix
+	}
+	return name + ".db"
+}
+
+// ListSnapshots 列出快照，按时间倒序
+func ListSnapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(SnapshotDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Snapshot{}, nil
+		}
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		createdAt, source, ok := parseSnapshotName(entry.Name())
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt, Source: source})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// SnapshotPath 返回快照文件路径，名称不合法或文件不存在时返回 ErrSnapshotNotFound
+func SnapshotPath(name string) (string, error) {
+	if _, _, ok := parseSnapshotName(name); !ok {
+		return "", ErrSnapshotNotFound
+	}
+	path := filepath.Join(SnapshotDir(), name)
+	if _, err := os.Stat(path); err != nil {
+		return "", ErrSnapshotNotFound
+	}
+	return path, nil
+}
+
+// RestoreSnapshot 用快照替换当前数据库，替换前会先为当前数据库生成一个快照
+func RestoreSnapshot(name string) (*Snapshot, error) {
+	path, err := SnapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	safety, err := CreateSnapshot("pre-restore")
+	if err != nil {
+		return nil, fmt.Errorf("恢复前备份当前数据库失败: %w", err)
+	}
+	if err := model.ReplaceDB(path); err != nil {
+		return safety, err
+	}
+	return safety, nil
+}
+
+// RotateSnapshots 按保留策略删除多余的定时快照，返回被删除的快照名称
+// 手动生成和恢复前自动生成的快照不参与轮换，需要手动删除
+func RotateSnapshots(retention SnapshotRetention) ([]string, error) {
+	if retention.KeepLast <= 0 && retention.KeepDaily <= 0 && retention.KeepWeekly <= 0 {
+		return nil, nil
+	}
+
+	all, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, snap := range all {
+		if snap.Source == SnapshotSourceAuto {
+			snapshots = append(snapshots, snap)
+		}
+	}
+
+	keep := make(map[string]bool)
+	for i, snap := range snapshots {
+		if i < retention.KeepLast {
+			keep[snap.Name] = true
+		}
+	}
+	// 快照已按时间倒序，每个周期遇到的第一个即为该周期最新的快照
+	keepPerPeriod(snapshots, retention.KeepDaily, keep, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepPerPeriod(snapshots, retention.KeepWeekly, keep, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+
+	var removed []string
+	for _, snap := range snapshots {
+		if keep[snap.Name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(SnapshotDir(), snap.Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, snap.Name)
+	}
+	return removed, nil
+}
+
+// keepPerPeriod 在最近 n 个周期内每个周期保留最新的快照
+func keepPerPeriod(snapshots []Snapshot, n int, keep map[string]bool, periodKey func(time.Time) string) {
+	if n <= 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, snap := range snapshots {
+		key := periodKey(snap.CreatedAt)
+		if seen[key] {
+			continue
+		}
+		if len(seen) >= n {
+			return
+		}
+		seen[key] = true
+		keep[snap.Name] = true
+	}
+}
+
+// parseSnapshotName 校验快照文件名并解析生成时间与来源
+func parseSnapshotName(name string) (time.Time, string, bool) {
+	if strings.ContainsAny(name, `/\`) {
+		return time.Time{}, "", false
+	}
+	m := snapshotNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, "", false
+	}
+	t, err := time.ParseInLocation(snapshotTimeLayout, m[1], time.Local)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	source := m[2]
+	if source == "" {
+		source = SnapshotSourceAuto
+	}
+	return t, source, true
+}
+
+// ParseSnapshotRetention 解析设置中保存的保留策略，无效值按 0 处理
+func ParseSnapshotRetention(keepLast, keepDaily, keepWeekly string) SnapshotRetention {
+	atoi := func(s string) int {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n
+	}
+	return SnapshotRetention{KeepLast: atoi(keepLast), KeepDaily: atoi(keepDaily), KeepWeekly: atoi(keepWeekly)}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateSnapshotUniqueNames(t *testing.T) {
+	t.Cleanup(func() { os.RemoveAll(SnapshotDir()) })
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		snap, err := CreateSnapshot("manual")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[snap.Name] {
+			t.Fatalf("快照名称重复: %s", snap.Name)
+		}
+		seen[snap.Name] = true
+		if snap.Source != "manual" {
+			t.Errorf("Source = %q, want manual", snap.Source)
+		}
+	}
+}
+
+func TestRotateSnapshotsSkipsSuffixed(t *testing.T) {
+	t.Cleanup(func() { os.RemoveAll(SnapshotDir()) })
+	if err := os.MkdirAll(SnapshotDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"subdock-20260302-033000.000.db",        // 定时快照
+		"subdock-20260303-033000.000.db",        // 定时快照
+		"subdock-20260301-120000.000-manual.db", // 手动快照
+		"subdock-20260304-120000.000-pre-restore.db",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(SnapshotDir(), name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := RotateSnapshots(SnapshotRetention{KeepLast: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"subdock-20260302-033000.000.db": true}
+	if len(removed) != len(want) {
+		t.Fatalf("removed = %v, want %v", removed, want)
+	}
+	for _, name := range removed {
+		if !want[name] {
+			t.Errorf("不应删除 %s", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+// StatsOptions 统计选项
+type StatsOptions struct {
+	Currency    string // 汇总使用的目标币种
+	ExcludeZero bool   // 是否排除金额为 0 的订阅
+	Top         int    // 花费排行数量
+}
+
+// SubscriptionCost 单个订阅的折算费用
+type SubscriptionCost struct {
+	ID          uint                     `json:"id"`
+	Name        string                   `json:"name"`
+	Amount      float64                  `json:"amount"`
+	Currency    string                   `json:"currency"`
+	BillingType model.BillingType        `json:"billing_type"`
+	CycleValue  int                      `json:"cycle_value"`
+	CycleUnit   model.CycleUnit          `json:"cycle_unit"`
+	Status      model.SubscriptionStatus `json:"status"`
+	CategoryID  *uint                    `json:"category_id"`
+	Tags        []string                 `json:"tags"`
+	Monthly     float64                  `json:"monthly"`
+	Yearly      float64                  `json:"yearly"`
+	MonthlyBase *float64                 `json:"monthly_base"` // 换算为目标币种，缺少汇率时为 null
+	YearlyBase  *float64                 `json:"yearly_base"`
+}
+
+// CurrencyTotal 按币种汇总
+type CurrencyTotal struct {
+	Currency string  `json:"currency"`
+	Count    int     `json:"count"`
+	Monthly  float64 `json:"monthly"`
+	Yearly   float64 `json:"yearly"`
+}
+
+// CategoryTotal 按分类汇总（换算为目标币种）
+type CategoryTotal struct {
+	CategoryID  *uint    `json:"category_id"` // 为空表示未分类
+	Name        string   `json:"name"`
+	Color       string   `json:"color"`
+	Icon        string   `json:"icon"`
+	Count       int      `json:"count"`
+	Monthly     float64  `json:"monthly"`
+	Yearly      float64  `json:"yearly"`
+	Unconverted []string `json:"unconverted"`
+}
+
+// CostTotal 换算为目标币种后的总计
+type CostTotal struct {
+	Currency    string   `json:"currency"`
+	Monthly     float64  `json:"monthly"`
+	Yearly      float64  `json:"yearly"`
+	Unconverted []string `json:"unconverted"` // 缺少汇率未计入总计的币种
+}
+
+// OneTimeTotal 一次性购买与永久授权的支出，按购买当日汇率换算为目标币种，不计入每月/每年费用
+type OneTimeTotal struct {
+	Currency    string   `json:"currency"`
+	Count       int      `json:"count"`
+	Amount      float64  `json:"amount"`      // 累计支出
+	ThisYear    float64  `json:"this_year"`   // 今年购买的支出
+	Unconverted []string `json:"unconverted"` // 缺少汇率未计入的币种
+}
+
+// Stats 费用统计结果
+type Stats struct {
+	GeneratedAt      time.Time          `json:"generated_at"`
+	Subscriptions    []SubscriptionCost `json:"subscriptions"`
+	TotalsByCurrency []CurrencyTotal    `json:"totals_by_currency"`
+	TotalsByCategory []CategoryTotal    `json:"totals_by_category"`
+	Total            CostTotal          `json:"total"`
+	OneTime          OneTimeTotal       `json:"one_time"`
+	TopSpenders      []SubscriptionCost `json:"top_spenders"`
+	StatusCounts     map[string]int     `json:"status_counts"`
+	Expired          int                `json:"expired"`       // 状态为 active 但已过期
+	ExpiringSoon     int                `json:"expiring_soon"` // 状态为 active 且处于提醒窗口内
+	Budgets          []BudgetStatus     `json:"budgets"`
+}
+
+// BuildStats 统计订阅费用，仅 active 状态的订阅计入费用汇总
+func BuildStats(opts StatsOptions) (*Stats, error) {
+	var subscriptions []model.Subscription
+	if err := model.GetDB().Preload("Category").Preload("Tags").Order("id asc").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	now := clock.Now()
+	target := strings.ToUpper(opts.Currency)
+	stats := &Stats{
+		GeneratedAt:      now,
+		Subscriptions:    []SubscriptionCost{},
+		TotalsByCurrency: []CurrencyTotal{},
+		TotalsByCategory: []CategoryTotal{},
+		TopSpenders:      []SubscriptionCost{},
+		StatusCounts: map[string]int{
+			string(model.StatusActive):    0,
+			string(model.StatusPaused):    0,
+			string(model.StatusCancelled): 0,
+		},
+		Total:   CostTotal{Currency: target, Unconverted: []string{}},
+		OneTime: OneTimeTotal{Currency: target, Unconverted: []string{}},
+	}
+
+	byCurrency := make(map[string]*CurrencyTotal)
+	byCategory := make(map[uint]*CategoryTotal) // 0 表示未分类
+	unconverted := make(map[string]bool)
+
+	for i := range subscriptions {
+		sub := &subscriptions[i]
+		if opts.ExcludeZero && sub.Amount == 0 {
+			continue
+		}
+
+		status := sub.Status
+		if status == "" {
+			status = model.StatusActive
+		}
+		stats.StatusCounts[string(status)]++
+
+		cost := SubscriptionCost{
+			ID:          sub.ID,
+			Name:        sub.Name,
+			Amount:      sub.Amount,
+			Currency:    sub.Currency,
+			CycleValue:  sub.CycleValue,
+			BillingType: sub.BillingType,
+			CycleUnit:   sub.CycleUnit,
+			Status:      status,
+			CategoryID:  sub.CategoryID,
+			Tags:        tagNames(sub.Tags),
+			Monthly:     roundMoney(sub.MonthlyCost()),
+			Yearly:      roundMoney(sub.YearlyCost()),
+		}
+		monthlyBase, convErr := ConvertAmount(sub.MonthlyCost(), sub.Currency, target, now)
+		if convErr == nil {
+			m, y := roundMoney(monthlyBase), roundMoney(monthlyBase*12)
+			cost.MonthlyBase, cost.YearlyBase = &m, &y
+		}
+		stats.Subscriptions = append(stats.Subscriptions, cost)
+
+		if status != model.StatusActive {
+			continue
+		}
+
+		// 按订阅所在时区判断是否过期
+		today := sub.Today()
+		if model.CalendarDate(sub.ExpireDate).Before(today) {
+			stats.Expired++
+		} else if sub.InReminderWindow(today) {
+			stats.ExpiringSoon++
+		}
+
+		if !sub.IsRecurring() {
+			stats.OneTime.Count++
+			amount, err := ConvertAmount(sub.Amount, sub.Currency, target, sub.StartDate)
+			if err != nil {
+				if !containsString(stats.OneTime.Unconverted, sub.Currency) {
+					stats.OneTime.Unconverted = append(stats.OneTime.Unconverted, sub.Currency)
+				}
+				continue
+			}
+			stats.OneTime.Amount += amount
+			if sub.StartDate.Year() == today.Year() {
+				stats.OneTime.ThisYear += amount
+			}
+			continue
+		}
+
+		total, ok := byCurrency[sub.Currency]
+		if !ok {
+			total = &CurrencyTotal{Currency: sub.Currency}
+			byCurrency[sub.Currency] = total
+		}
+		total.Count++
+		total.Monthly += sub.MonthlyCost()
+		total.Yearly += sub.YearlyCost()
+
+		categoryKey := uint(0)
+		if sub.CategoryID != nil {
+			categoryKey = *sub.CategoryID
+		}
+		categoryTotal, ok := byCategory[categoryKey]
+		if !ok {
+			categoryTotal = &CategoryTotal{Name: "未分类", Unconverted: []string{}}
+			if sub.Category != nil {
+				categoryTotal.CategoryID = sub.CategoryID
+				categoryTotal.Name = sub.Category.Name
+				categoryTotal.Color = sub.Category.Color
+				categoryTotal.Icon = sub.Category.Icon
+			}
+			byCategory[categoryKey] = categoryTotal
+		}
+		categoryTotal.Count++
+
+		if convErr == nil {
+			categoryTotal.Monthly += monthlyBase
+			stats.Total.Monthly += monthlyBase
+			stats.TopSpenders = append(stats.TopSpenders, cost)
+		} else {
+			unconverted[sub.Currency] = true
+			if !containsString(categoryTotal.Unconverted, sub.Currency) {
+				categoryTotal.Unconverted = append(categoryTotal.Unconverted, sub.Currency)
+			}
+		}
+	}
+
+	for _, total := range byCurrency {
+		total.Monthly = roundMoney(total.Monthly)
+		total.Yearly = roundMoney(total.Yearly)
+		stats.TotalsByCurrency = append(stats.TotalsByCurrency, *total)
+	}
+	sort.Slice(stats.TotalsByCurrency, func(i, j int) bool {
+		return stats.TotalsByCurrency[i].Currency < stats.TotalsByCurrency[j].Currency
+	})
+
+	for _, total := range byCategory {
+		total.Yearly = roundMoney(total.Monthly * 12)
+		total.Monthly = roundMoney(total.Monthly)
+		stats.TotalsByCategory = append(stats.TotalsByCategory, *total)
+	}
+	sort.Slice(stats.TotalsByCategory, func(i, j int) bool {
+		return stats.TotalsByCategory[i].Monthly > stats.TotalsByCategory[j].Monthly
+	})
+
+	for currency := range unconverted {
+		stats.Total.Unconverted = append(stats.Total.Unconverted, currency)
+	}
+	sort.Strings(stats.Total.Unconverted)
+	sort.Strings(stats.OneTime.Unconverted)
+	stats.OneTime.Amount = roundMoney(stats.OneTime.Amount)
+	stats.OneTime.ThisYear = roundMoney(stats.OneTime.ThisYear)
+	stats.Total.Yearly = roundMoney(stats.Total.Monthly * 12)
+	stats.Total.Monthly = roundMoney(stats.Total.Monthly)
+
+	sort.SliceStable(stats.TopSpenders, func(i, j int) bool {
+		return *stats.TopSpenders[i].MonthlyBase > *stats.TopSpenders[j].MonthlyBase
+	})
+	if opts.Top > 0 && len(stats.TopSpenders) > opts.Top {
+		stats.TopSpenders = stats.TopSpenders[:opts.Top]
+	}
+
+	budgets, err := BudgetStatuses(now)
+	if err != nil {
+		return nil, err
+	}
+	stats.Budgets = budgets
+
+	return stats, nil
+}
+
+// tagNames 提取标签名称
+func tagNames(tags []model.Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// roundMoney 金额保留两位小数
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+func TestBuildStatsBreakdowns(t *testing.T) {
+	resetSubscriptions(t)
+	resetRates(t)
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+
+	category := model.Category{Name: "影音"}
+	if err := model.GetDB().Create(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	subs := []struct {
+		name     string
+		amount   float64
+		currency string
+		value    int
+		unit     model.CycleUnit
+		status   model.SubscriptionStatus
+		category *uint
+		monthly  float64
+		yearly   float64
+	}{
+		{"月付", 30, "CNY", 1, model.CycleUnitMonth, model.StatusActive, &category.ID, 30, 360},
+		{"三个季度", 90, "CNY", 3, model.CycleUnitQuarter, model.StatusActive, &category.ID, 10, 120},
+		{"半年", 60, "CNY", 1, model.CycleUnitHalfYear, model.StatusActive, nil, 10, 120},
+		{"两年", 240, "CNY", 2, model.CycleUnitYear, model.StatusActive, nil, 10, 120},
+		{"每周", 7, "CNY", 1, model.CycleUnitWeek, model.StatusActive, nil, 30.44, 365.25},
+		{"美元", 12, "USD", 1, model.CycleUnitYear, model.StatusActive, nil, 1, 12},
+		{"已暂停", 100, "CNY", 1, model.CycleUnitMonth, model.StatusPaused, nil, 100, 1200},
+		{"免费", 0, "CNY", 1, model.CycleUnitMonth, model.StatusActive, nil, 0, 0},
+	}
+	for _, s := range subs {
+		sub := model.Subscription{
+			Name:        s.name,
+			Amount:      s.amount,
+			Currency:    s.currency,
+			StartDate:   date("2026-03-01"),
+			BillingType: model.BillingRecurring,
+			CycleValue:  s.value,
+			CycleUnit:   s.unit,
+			AnchorDay:   1,
+			ExpireDate:  date("2026-12-01"),
+			Status:      s.status,
+			CategoryID:  s.category,
+		}
+		if err := model.GetDB().Create(&sub).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := BuildStats(StatsOptions{Currency: "cny", Top: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Subscriptions) != len(subs) {
+		t.Fatalf("len(subscriptions) = %d, want %d", len(stats.Subscriptions), len(subs))
+	}
+	for i, s := range subs {
+		got := stats.Subscriptions[i]
+		if got.Name != s.name || math.Abs(got.Monthly-s.monthly) > 0.005 || math.Abs(got.Yearly-s.yearly) > 0.005 {
+			t.Errorf("%s monthly/yearly = %v/%v, want %v/%v", s.name, got.Monthly, got.Yearly, s.monthly, s.yearly)
+		}
+	}
+
+	// 缺少汇率的币种不计入总计，暂停的订阅只计入状态统计
+	if stats.Total.Currency != "CNY" || stats.Total.Monthly != 90.44 || stats.Total.Yearly != 1085.25 {
+		t.Errorf("total = %+v, want 90.44/1085.25 CNY", stats.Total)
+	}
+	if len(stats.Total.Unconverted) != 1 || stats.Total.Unconverted[0] != "USD" {
+		t.Errorf("unconverted = %v, want [USD]", stats.Total.Unconverted)
+	}
+
+	wantCurrencies := map[string]CurrencyTotal{
+		"CNY": {Currency: "CNY", Count: 6, Monthly: 90.44, Yearly: 1085.25},
+		"USD": {Currency: "USD", Count: 1, Monthly: 1, Yearly: 12},
+	}
+	if len(stats.TotalsByCurrency) != len(wantCurrencies) {
+		t.Errorf("totals_by_currency = %+v", stats.TotalsByCurrency)
+	}
+	for _, got := range stats.TotalsByCurrency {
+		if got != wantCurrencies[got.Currency] {
+			t.Errorf("totals_by_currency[%s] = %+v, want %+v", got.Currency, got, wantCurrencies[got.Currency])
+		}
+	}
+
+	wantCategories := []struct {
+		name    string
+		count   int
+		monthly float64
+		yearly  float64
+	}{
+		{"未分类", 5, 50.44, 605.25},
+		{"影音", 2, 40, 480},
+	}
+	if len(stats.TotalsByCategory) != len(wantCategories) {
+		t.Fatalf("totals_by_category = %+v", stats.TotalsByCategory)
+	}
+	for i, want := range wantCategories {
+		got := stats.TotalsByCategory[i]
+		if got.Name != want.name || got.Count != want.count || got.Monthly != want.monthly || got.Yearly != want.yearly {
+			t.Errorf("totals_by_category[%d] = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if len(stats.TopSpenders) != 2 || stats.TopSpenders[0].Name != "每周" || stats.TopSpenders[1].Name != "月付" {
+		t.Errorf("top_spenders = %+v, want 每周 and 月付", stats.TopSpenders)
+	}
+	if stats.StatusCounts[string(model.StatusActive)] != 7 || stats.StatusCounts[string(model.StatusPaused)] != 1 {
+		t.Errorf("status_counts = %v", stats.StatusCounts)
+	}
+
+	excluded, err := BuildStats(StatsOptions{Currency: "CNY", ExcludeZero: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(excluded.Subscriptions) != len(subs)-1 || excluded.StatusCounts[string(model.StatusActive)] != 6 {
+		t.Errorf("exclude_zero: %d subscriptions, status_counts = %v", len(excluded.Subscriptions), excluded.StatusCounts)
+	}
+}
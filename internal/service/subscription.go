@@ -0,0 +1,93 @@
+package service
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+// RenewSubscription 手动续订一个周期，从当前到期日期起算
+// HTTP 接口与 Telegram 机器人共用，订阅不存在时返回 gorm.ErrRecordNotFound
+func RenewSubscription(id uint) (*model.Subscription, error) {
+	var subscription model.Subscription
+	err := model.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&subscription, id).Error; err != nil {
+			return err
+		}
+		_, err := subscription.Renew(tx, subscription.ExpireDate)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// SnoozeSubscription 暂停提醒 days 天（从订阅时区下的今天起算），订阅不存在时返回 gorm.ErrRecordNotFound
+func SnoozeSubscription(id uint, days int) (*model.Subscription, error) {
+	var subscription model.Subscription
+	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return SnoozeSubscriptionUntil(id, subscription.Today().AddDate(0, 0, days))
+}
+
+// SnoozeSubscriptionUntil 暂停提醒到 until（不含），until 按日历日期保存
+func SnoozeSubscriptionUntil(id uint, until time.Time) (*model.Subscription, error) {
+	return updateReminderState(id, map[string]interface{}{"snooze_until": model.CalendarDate(until)})
+}
+
+// UnsnoozeSubscription 取消提醒暂停
+func UnsnoozeSubscription(id uint) (*model.Subscription, error) {
+	return updateReminderState(id, map[string]interface{}{"snooze_until": nil})
+}
+
+// AcknowledgeSubscription 确认已知晓当前的到期或支持/更新截止提醒，续订或日期变化后自动失效
+func AcknowledgeSubscription(id uint) (*model.Subscription, error) {
+	var subscription model.Subscription
+	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return updateReminderState(id, map[string]interface{}{
+		"acked_at":     clock.Now(),
+		"acked_expire": subscription.DueDate(subscription.Today()),
+	})
+}
+
+// UnacknowledgeSubscription 撤销本周期的提醒确认
+func UnacknowledgeSubscription(id uint) (*model.Subscription, error) {
+	return updateReminderState(id, map[string]interface{}{"acked_at": nil, "acked_expire": nil})
+}
+
+// updateReminderState 更新订阅的提醒状态并返回更新后的订阅
+func updateReminderState(id uint, updates map[string]interface{}) (*model.Subscription, error) {
+	var subscription model.Subscription
+	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	if err := model.GetDB().Model(&subscription).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// CancelSubscription 将订阅标记为已取消，订阅不存在时返回 gorm.ErrRecordNotFound
+func CancelSubscription(id uint) (*model.Subscription, error) {
+	var subscription model.Subscription
+	if err := model.GetDB().First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+
+	if err := model.GetDB().Model(&subscription).Update("status", model.StatusCancelled).Error; err != nil {
+		return nil, err
+	}
+	subscription.Status = model.StatusCancelled
+	return &subscription, nil
+}
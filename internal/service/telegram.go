@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTelegramAPIURL Telegram Bot API 默认地址
+const DefaultTelegramAPIURL = "https://api.telegram.org"
+
+// TelegramClient Telegram Bot API 客户端，APIURL 可指向自建或测试用的 Bot API 服务
+type TelegramClient struct {
+	APIURL string
+	Token  string
+	client *http.Client
+}
+
+// TelegramInlineButton 内联键盘按钮
+type TelegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// TelegramInlineKeyboard 内联键盘
+type TelegramInlineKeyboard struct {
+	InlineKeyboard [][]TelegramInlineButton `json:"inline_keyboard"`
+}
+
+// TelegramChat 会话
+type TelegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramMessage 消息
+type TelegramMessage struct {
+	MessageID int64        `json:"message_id"`
+	Chat      TelegramChat `json:"chat"`
+	Text      string       `json:"text"`
+}
+
+// TelegramCallbackQuery 内联按钮回调
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	Message *TelegramMessage `json:"message"`
+	Data    string           `json:"data"`
+}
+
+// TelegramUpdate getUpdates 返回的更新
+type TelegramUpdate struct {
+	UpdateID      int64                  `json:"update_id"`
+	Message       *TelegramMessage       `json:"message"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query"`
+}
+
+// telegramResponse Bot API 通用响应
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// NewTelegramClient 创建 Telegram 客户端，apiURL 为空时使用官方地址
+func NewTelegramClient(apiURL, token string) *TelegramClient {
+	if apiURL == "" {
+		apiURL = DefaultTelegramAPIURL
+	}
+	return &TelegramClient{
+		APIURL: strings.TrimRight(apiURL, "/"),
+		Token:  token,
+		// 长轮询请求会挂起到超时时间，客户端超时需大于轮询超时
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// SendMessage 发送消息，keyboard 为空时不附带按钮
+func (t *TelegramClient) SendMessage(ctx context.Context, chatID, text string, keyboard *TelegramInlineKeyboard) error {
+	payload := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	if keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
+	return t.call(ctx, "sendMessage", payload, nil)
+}
+
+// EditMessageText 修改已发送的消息并移除按钮
+func (t *TelegramClient) EditMessageText(ctx context.Context, chatID, messageID int64, text string) error {
+	return t.call(ctx, "editMessageText", map[string]interface{}{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       text,
+	}, nil)
+}
+
+// AnswerCallbackQuery 响应按钮回调
+func (t *TelegramClient) AnswerCallbackQuery(ctx context.Context, id, text string) error {
+	return t.call(ctx, "answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": id,
+		"text":              text,
+	}, nil)
+}
+
+// GetUpdates 长轮询获取更新，timeout 为服务端挂起的秒数
+func (t *TelegramClient) GetUpdates(ctx context.Context, offset int64, timeout int) ([]TelegramUpdate, error) {
+	var updates []TelegramUpdate
+	err := t.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         timeout,
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+// call 调用 Bot API 方法
+func (t *TelegramClient) call(ctx context.Context, method string, payload interface{}, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/bot%s/%s", t.APIURL, t.Token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var res telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || resp.StatusCode != http.StatusOK || !res.OK {
+		if res.Description != "" {
+			return fmt.Errorf("Telegram API 返回错误: %d %s", resp.StatusCode, res.Description)
+		}
+		return fmt.Errorf("Telegram API 返回错误: %d", resp.StatusCode)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(res.Result, result); err != nil {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+	return nil
+}
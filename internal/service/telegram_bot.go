@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"subdock/internal/model"
+)
+
+// telegramPollTimeout 长轮询挂起秒数
+const telegramPollTimeout = 30
+
+// 提醒消息按钮的回调动作，回调数据格式为 "动作:订阅ID"
+const (
+	callbackRenew  = "renew"
+	callbackSnooze = "snooze"
+	callbackCancel = "cancel"
+)
+
+// telegramBotHelp 机器人帮助信息
+const telegramBotHelp = `SubDock 机器人命令：
+/list - 列出所有生效中的订阅
+/upcoming [天数] - 列出即将到期的订阅（默认 30 天）
+/renew <ID> - 续订一个周期
+/snooze <ID> <天数> - 暂停提醒若干天
+/ack <ID> - 已知晓本周期提醒，续订前不再提醒
+/stats - 费用统计`
+
+// TelegramBot 处理 Telegram 命令与按钮回调，只响应授权的会话
+type TelegramBot struct {
+	Client       *TelegramClient
+	AllowedChats map[int64]bool
+	Currency     string // /stats 汇总使用的币种
+}
+
+// NewTelegramBot 创建 Telegram 机器人
+func NewTelegramBot(client *TelegramClient, allowedChats map[int64]bool, currency string) *TelegramBot {
+	return &TelegramBot{Client: client, AllowedChats: allowedChats, Currency: currency}
+}
+
+// ParseChatIDs 解析逗号分隔的会话 ID 列表，忽略无效项
+func ParseChatIDs(values ...string) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if id, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64); err == nil {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// ReminderKeyboard 到期提醒附带的按钮：已续订、暂停提醒 1 天、取消订阅
+func ReminderKeyboard(subscriptionID uint) *TelegramInlineKeyboard {
+	id := strconv.FormatUint(uint64(subscriptionID), 10)
+	return &TelegramInlineKeyboard{InlineKeyboard: [][]TelegramInlineButton{{
+		{Text: "✅ 已续订", CallbackData: callbackRenew + ":" + id},
+		{Text: "😴 暂停 1 天", CallbackData: callbackSnooze + ":" + id},
+		{Text: "❌ 取消订阅", CallbackData: callbackCancel + ":" + id},
+	}}}
+}
+
+// Poll 执行一次长轮询并处理收到的更新，返回下一次轮询的 offset
+func (b *TelegramBot) Poll(ctx context.Context, offset int64) (int64, error) {
+	updates, err := b.Client.GetUpdates(ctx, offset, telegramPollTimeout)
+	if err != nil {
+		return offset, err
+	}
+	for _, update := range updates {
+		b.HandleUpdate(ctx, update)
+		if update.UpdateID >= offset {
+			offset = update.UpdateID + 1
+		}
+	}
+	return offset, nil
+}
+
+// HandleUpdate 处理一条更新，未授权会话的消息直接忽略
+func (b *TelegramBot) HandleUpdate(ctx context.Context, update TelegramUpdate) {
+	switch {
+	case update.CallbackQuery != nil:
+		b.handleCallback(ctx, update.CallbackQuery)
+	case update.Message != nil:
+		if !b.AllowedChats[update.Message.Chat.ID] {
+			return
+		}
+		reply := b.handleCommand(update.Message.Text)
+		if reply == "" {
+			return
+		}
+		if err := b.Client.SendMessage(ctx, strconv.FormatInt(update.Message.Chat.ID, 10), reply, nil); err != nil {
+			log.Printf("回复 Telegram 消息失败: %v", err)
+		}
+	}
+}
+
+// handleCommand 执行命令并返回回复内容，非命令消息返回空字符串
+func (b *TelegramBot) handleCommand(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	// 群组中的命令可能带有 @机器人用户名 后缀
+	command, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	args := fields[1:]
+
+	switch strings.ToLower(command) {
+	case "start", "help":
+		return telegramBotHelp
+	case "list":
+		return b.listSubscriptions(0)
+	case "upcoming":
+		days := 30
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 || n > 365 {
+				return "天数应为 1-365"
+			}
+			days = n
+		}
+		return b.listSubscriptions(days)
+	case "renew":
+		if len(args) != 1 {
+			return "用法: /renew <ID>"
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return "无效的 ID"
+		}
+		return b.renew(uint(id))
+	case "snooze":
+		if len(args) != 2 {
+			return "用法: /snooze <ID> <天数>"
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return "无效的 ID"
+		}
+		days, err := strconv.Atoi(args[1])
+		if err != nil || days <= 0 || days > 365 {
+			return "天数应为 1-365"
+		}
+		return b.snooze(uint(id), days)
+	case "ack":
+		if len(args) != 1 {
+			return "用法: /ack <ID>"
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return "无效的 ID"
+		}
+		sub, err := AcknowledgeSubscription(uint(id))
+		if err != nil {
+			return subscriptionActionError(err, "确认提醒失败")
+		}
+		return fmt.Sprintf("👌 已确认 %s 的到期提醒，本周期内不再提醒", sub.Name)
+	case "stats":
+		return b.stats()
+	default:
+		return "未知命令\n\n" + telegramBotHelp
+	}
+}
+
+// handleCallback 处理提醒消息上的按钮
+func (b *TelegramBot) handleCallback(ctx context.Context, query *TelegramCallbackQuery) {
+	if query.Message == nil || !b.AllowedChats[query.Message.Chat.ID] {
+		b.Client.AnswerCallbackQuery(ctx, query.ID, "未授权")
+		return
+	}
+
+	action, idStr, _ := strings.Cut(query.Data, ":")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		b.Client.AnswerCallbackQuery(ctx, query.ID, "无效的操作")
+		return
+	}
+
+	var result string
+	switch action {
+	case callbackRenew:
+		result = b.renew(uint(id))
+	case callbackSnooze:
+		result = b.snooze(uint(id), 1)
+	case callbackCancel:
+		result = b.cancel(uint(id))
+	default:
+		b.Client.AnswerCallbackQuery(ctx, query.ID, "无效的操作")
+		return
+	}
+
+	if err := b.Client.AnswerCallbackQuery(ctx, query.ID, result); err != nil {
+		log.Printf("响应 Telegram 回调失败: %v", err)
+	}
+	// 在原提醒下追加处理结果并移除按钮，避免重复操作
+	text := query.Message.Text + "\n\n" + result
+	if err := b.Client.EditMessageText(ctx, query.Message.Chat.ID, query.Message.MessageID, text); err != nil {
+		log.Printf("更新 Telegram 消息失败: %v", err)
+	}
+}
+
+// listSubscriptions 列出生效中的订阅，days 大于 0 时只列出该天数内到期的
+func (b *TelegramBot) listSubscriptions(days int) string {
+	query := model.GetDB().Where("status = ?", model.StatusActive).Order("expire_date asc").Order("id asc")
+	title := "📋 生效中的订阅"
+	if days > 0 {
+		until := model.Today(model.GlobalLocation()).AddDate(0, 0, days+1)
+		query = query.Where("expire_date < ?", until)
+		title = fmt.Sprintf("⏰ %d 天内到期的订阅", days)
+	}
+
+	var subscriptions []model.Subscription
+	if err := query.Limit(50).Find(&subscriptions).Error; err != nil {
+		return "获取订阅列表失败"
+	}
+	if len(subscriptions) == 0 {
+		return title + "\n\n暂无订阅"
+	}
+
+	lines := []string{title, ""}
+	for _, sub := range subscriptions {
+		lines = append(lines, fmt.Sprintf("#%d %s  %.2f %s  到期 %s",
+			sub.ID, sub.Name, sub.Amount, sub.Currency, sub.ExpireDateText()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renew 续订订阅，与 HTTP 续订接口逻辑一致
+func (b *TelegramBot) renew(id uint) string {
+	sub, err := RenewSubscription(id)
+	if err != nil {
+		return subscriptionActionError(err, "续订失败")
+	}
+	return fmt.Sprintf("✅ %s 已续订，新到期日期 %s", sub.Name, sub.ExpireDate.Format("2006-01-02"))
+}
+
+// snooze 暂停提醒
+func (b *TelegramBot) snooze(id uint, days int) string {
+	sub, err := SnoozeSubscription(id, days)
+	if err != nil {
+		return subscriptionActionError(err, "暂停提醒失败")
+	}
+	return fmt.Sprintf("😴 %s 的提醒已暂停至 %s", sub.Name, sub.SnoozeUntil.Format("2006-01-02"))
+}
+
+// cancel 取消订阅
+func (b *TelegramBot) cancel(id uint) string {
+	sub, err := CancelSubscription(id)
+	if err != nil {
+		return subscriptionActionError(err, "取消订阅失败")
+	}
+	return fmt.Sprintf("❌ %s 已取消", sub.Name)
+}
+
+// stats 费用统计摘要
+func (b *TelegramBot) stats() string {
+	stats, err := BuildStats(StatsOptions{Currency: b.Currency})
+	if err != nil {
+		return "获取统计失败"
+	}
+
+	text := fmt.Sprintf("📊 费用统计\n\n生效中: %d\n已暂停: %d\n已取消: %d\n即将到期: %d\n已过期: %d\n\n月均: %.2f %s\n年均: %.2f %s",
+		stats.StatusCounts[string(model.StatusActive)],
+		stats.StatusCounts[string(model.StatusPaused)],
+		stats.StatusCounts[string(model.StatusCancelled)],
+		stats.ExpiringSoon, stats.Expired,
+		stats.Total.Monthly, stats.Total.Currency,
+		stats.Total.Yearly, stats.Total.Currency)
+	if len(stats.Total.Unconverted) > 0 {
+		text += "\n未换算币种: " + strings.Join(stats.Total.Unconverted, ", ")
+	}
+	return text
+}
+
+// subscriptionActionError 将订阅操作错误转换为回复内容
+func subscriptionActionError(err error, fallback string) string {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "订阅不存在"
+	}
+	if errors.Is(err, model.ErrCycleExhausted) || errors.Is(err, model.ErrNotRenewable) {
+		return err.Error()
+	}
+	log.Printf("%s: %v", fallback, err)
+	return fallback
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"subdock/internal/clock"
+	"subdock/internal/model"
+)
+
+const (
+	testBotToken   = "test-token"
+	testChatID     = int64(100)
+	strangerChatID = int64(999)
+)
+
+// botCall Bot API 收到的一次调用
+type botCall struct {
+	Method  string
+	Payload map[string]interface{}
+}
+
+// fakeBotAPI 模拟 Telegram Bot API：getUpdates 依次返回排队的更新，其余方法记录调用并返回成功
+type fakeBotAPI struct {
+	*httptest.Server
+	mu      sync.Mutex
+	updates []TelegramUpdate
+	calls   []botCall
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	t.Helper()
+	api := &fakeBotAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testBotToken+"/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "description": "Not Found"})
+			return
+		}
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		var result interface{} = true
+		if method == "getUpdates" {
+			result, api.updates = api.updates, nil
+		} else {
+			api.calls = append(api.calls, botCall{Method: method, Payload: payload})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// push 排队一条更新，update_id 自增
+func (api *fakeBotAPI) push(update TelegramUpdate) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	update.UpdateID = int64(len(api.updates) + 1)
+	api.updates = append(api.updates, update)
+}
+
+// takeCalls 返回并清空已记录的调用
+func (api *fakeBotAPI) takeCalls() []botCall {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	calls := api.calls
+	api.calls = nil
+	return calls
+}
+
+func message(chatID int64, text string) TelegramUpdate {
+	return TelegramUpdate{Message: &TelegramMessage{MessageID: 1, Chat: TelegramChat{ID: chatID}, Text: text}}
+}
+
+func callback(chatID int64, data string) TelegramUpdate {
+	return TelegramUpdate{CallbackQuery: &TelegramCallbackQuery{
+		ID:      "cb-" + data,
+		Message: &TelegramMessage{MessageID: 7, Chat: TelegramChat{ID: chatID}, Text: "📢 订阅到期提醒"},
+		Data:    data,
+	}}
+}
+
+// poll 将 updates 交给机器人处理一次，返回机器人发出的调用
+func poll(t *testing.T, api *fakeBotAPI, updates ...TelegramUpdate) []botCall {
+	t.Helper()
+	for _, u := range updates {
+		api.push(u)
+	}
+	bot := NewTelegramBot(NewTelegramClient(api.URL, testBotToken), map[int64]bool{testChatID: true}, "CNY")
+	offset, err := bot.Poll(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(updates)); len(updates) > 0 && offset != want+1 {
+		t.Errorf("offset = %d, want %d", offset, want+1)
+	}
+	return api.takeCalls()
+}
+
+// onlyCall 断言只有一次指定方法的调用并返回其参数
+func onlyCall(t *testing.T, calls []botCall, method string) map[string]interface{} {
+	t.Helper()
+	if len(calls) != 1 || calls[0].Method != method {
+		t.Fatalf("calls = %+v, want one %s", calls, method)
+	}
+	return calls[0].Payload
+}
+
+// callbackCalls 断言按钮回调依次响应回调并编辑原消息，返回响应文字与编辑后的消息
+func callbackCalls(t *testing.T, calls []botCall) (answer, edited string) {
+	t.Helper()
+	if len(calls) != 2 || calls[0].Method != "answerCallbackQuery" || calls[1].Method != "editMessageText" {
+		t.Fatalf("calls = %+v, want answerCallbackQuery and editMessageText", calls)
+	}
+	if got := calls[1].Payload["message_id"]; got != float64(7) {
+		t.Errorf("edited message_id = %v, want 7", got)
+	}
+	return calls[0].Payload["text"].(string), calls[1].Payload["text"].(string)
+}
+
+func setupTelegramTest(t *testing.T) *fakeBotAPI {
+	t.Helper()
+	resetSubscriptions(t)
+	t.Cleanup(clock.Set(clock.NewFixed(time.Date(2026, 3, 20, 9, 0, 0, 0, time.UTC))))
+	return newFakeBotAPI(t)
+}
+
+func TestTelegramBotIgnoresUnauthorizedChats(t *testing.T) {
+	api := setupTelegramTest(t)
+	sub := createSubscription(t, "Netflix", 10, "2026-03-25")
+
+	calls := poll(t, api,
+		message(strangerChatID, "/list"),
+		message(strangerChatID, "/renew "+strconv.Itoa(int(sub.ID))),
+		callback(strangerChatID, "cancel:"+strconv.Itoa(int(sub.ID))),
+	)
+	// 未授权会话的消息不回复，按钮回调只提示未授权
+	payload := onlyCall(t, calls, "answerCallbackQuery")
+	if payload["text"] != "未授权" {
+		t.Errorf("answer text = %v, want 未授权", payload["text"])
+	}
+	got := reload(t, sub.ID)
+	if got.RenewCount != 0 || got.Status != model.StatusActive {
+		t.Errorf("未授权操作不应修改订阅: renew_count = %d, status = %s", got.RenewCount, got.Status)
+	}
+}
+
+func TestTelegramBotRenewCommandAndButton(t *testing.T) {
+	api := setupTelegramTest(t)
+	sub := createSubscription(t, "Netflix", 10, "2026-03-25")
+	id := strconv.Itoa(int(sub.ID))
+
+	payload := onlyCall(t, poll(t, api, message(testChatID, "/renew "+id)), "sendMessage")
+	if want := "✅ Netflix 已续订，新到期日期 2026-04-25"; payload["text"] != want {
+		t.Errorf("reply = %v, want %q", payload["text"], want)
+	}
+	if payload["chat_id"] != strconv.FormatInt(testChatID, 10) {
+		t.Errorf("chat_id = %v, want %d", payload["chat_id"], testChatID)
+	}
+
+	answer, edited := callbackCalls(t, poll(t, api, callback(testChatID, "renew:"+id)))
+	want := "✅ Netflix 已续订，新到期日期 2026-05-25"
+	if answer != want || edited != "📢 订阅到期提醒\n\n"+want {
+		t.Errorf("answer = %q, edited = %q", answer, edited)
+	}
+
+	// 命令与按钮都通过 RenewSubscription 手动续订，各写入一条续订记录
+	var renewals []model.SubscriptionRenewal
+	if err := model.GetDB().Where("subscription_id = ?", sub.ID).Order("id asc").Find(&renewals).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(renewals) != 2 || renewals[0].Auto || renewals[1].RenewCount != 2 {
+		t.Errorf("renewals = %+v, want two manual renewals", renewals)
+	}
+	if got := reload(t, sub.ID); got.RenewCount != 2 || !got.ExpireDate.Equal(date("2026-05-25")) {
+		t.Errorf("renew_count = %d, expire_date = %s", got.RenewCount, got.ExpireDate.Format("2006-01-02"))
+	}
+}
+
+func TestTelegramBotSnoozeAndCancel(t *testing.T) {
+	api := setupTelegramTest(t)
+	sub := createSubscription(t, "Netflix", 10, "2026-03-25")
+	id := strconv.Itoa(int(sub.ID))
+
+	payload := onlyCall(t, poll(t, api, message(testChatID, "/snooze "+id+" 3")), "sendMessage")
+	if want := "😴 Netflix 的提醒已暂停至 2026-03-23"; payload["text"] != want {
+		t.Errorf("reply = %v, want %q", payload["text"], want)
+	}
+	if got := reload(t, sub.ID); got.SnoozeUntil == nil || !got.SnoozeUntil.Equal(date("2026-03-23")) {
+		t.Errorf("snooze_until = %v, want 2026-03-23", got.SnoozeUntil)
+	}
+
+	_, edited := callbackCalls(t, poll(t, api, callback(testChatID, "snooze:"+id)))
+	if !strings.HasSuffix(edited, "😴 Netflix 的提醒已暂停至 2026-03-21") {
+		t.Errorf("edited = %q", edited)
+	}
+	if got := reload(t, sub.ID); got.SnoozeUntil == nil || !got.SnoozeUntil.Equal(date("2026-03-21")) {
+		t.Errorf("snooze_until = %v, want 2026-03-21", got.SnoozeUntil)
+	}
+
+	_, edited = callbackCalls(t, poll(t, api, callback(testChatID, "cancel:"+id)))
+	if !strings.HasSuffix(edited, "❌ Netflix 已取消") {
+		t.Errorf("edited = %q", edited)
+	}
+	if got := reload(t, sub.ID); got.Status != model.StatusCancelled {
+		t.Errorf("status = %s, want cancelled", got.Status)
+	}
+}
+
+func TestTelegramBotListUpcomingStats(t *testing.T) {
+	api := setupTelegramTest(t)
+	soon := createSubscription(t, "Netflix", 10, "2026-03-25")
+	later := createSubscription(t, "iCloud", 20, "2026-06-01")
+
+	reply := func(text string) string {
+		t.Helper()
+		return onlyCall(t, poll(t, api, message(testChatID, text)), "sendMessage")["text"].(string)
+	}
+
+	list := reply("/list")
+	for _, want := range []string{"📋 生效中的订阅", "#" + strconv.Itoa(int(soon.ID)) + " Netflix  10.00 CNY  到期 2026-03-25", "#" + strconv.Itoa(int(later.ID)) + " iCloud"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("/list reply %q missing %q", list, want)
+		}
+	}
+
+	upcoming := reply("/upcoming 30")
+	if !strings.HasPrefix(upcoming, "⏰ 30 天内到期的订阅") || !strings.Contains(upcoming, "Netflix") || strings.Contains(upcoming, "iCloud") {
+		t.Errorf("/upcoming reply = %q", upcoming)
+	}
+	if got := reply("/upcoming 0"); got != "天数应为 1-365" {
+		t.Errorf("/upcoming 0 reply = %q", got)
+	}
+
+	stats := reply("/stats")
+	for _, want := range []string{"生效中: 2", "月均: 30.00 CNY", "年均: 360.00 CNY"} {
+		if !strings.Contains(stats, want) {
+			t.Errorf("/stats reply %q missing %q", stats, want)
+		}
+	}
+
+	// 普通消息不回复，未知命令回复帮助
+	if calls := poll(t, api, message(testChatID, "hello")); len(calls) != 0 {
+		t.Errorf("calls = %+v, want none", calls)
+	}
+	if got := reply("/unknown"); !strings.Contains(got, "/renew <ID>") {
+		t.Errorf("/unknown reply = %q", got)
+	}
+}
@@ -23,7 +23,7 @@ func main() {
 	sched.Start()
 	defer sched.Stop()
 
-	r := router.Setup()
+	r := router.Setup(sched)
 
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	log.Printf("服务器启动在 http://localhost%s", addr)
@@ -18,7 +18,7 @@ export interface Subscription {
   expire_date: string | null // YYYY-MM-DD or null
   auto_renew?: boolean
   renew_count?: number
-  remind_days: number
+  remind_offsets?: string // 逗号分隔的提醒偏移天数，为空使用全局默认
   remark?: string
 }
 